@@ -0,0 +1,322 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	notifier "github.com/minio/minio/cmd/event-notifier"
+
+	"github.com/gorilla/mux"
+)
+
+// errNotifyTargetNotConfigured - a QueueConfiguration named a target
+// id that has no matching (and enabled) section in the server config.
+var errNotifyTargetNotConfigured = errors.New("notifier: target is not configured")
+
+// notificationFilterRule - an S3 `<Filter><S3Key><FilterRule>` entry,
+// used to scope a configuration to keys matching a prefix/suffix.
+type notificationFilterRule struct {
+	Name  string `xml:"Name"`
+	Value string `xml:"Value"`
+}
+
+// notificationConfig - a single <QueueConfiguration>/<TopicConfiguration>
+// style entry: which target receives which events for which keys.
+type notificationConfig struct {
+	ID       string                   `xml:"Id"`
+	TargetID string                  `xml:"Queue"`
+	Events   []string                `xml:"Event"`
+	Filters  []notificationFilterRule `xml:"Filter>S3Key>FilterRule"`
+}
+
+// bucketNotificationXML - the PutBucketNotification request/response
+// body, modeled after the S3 NotificationConfiguration document.
+type bucketNotificationXML struct {
+	XMLName              xml.Name              `xml:"NotificationConfiguration"`
+	QueueConfigurations  []notificationConfig `xml:"QueueConfiguration"`
+}
+
+// bucketNotifiers - the live BucketNotifier for every bucket that has
+// a notification configuration set, keyed by bucket name.
+var (
+	bucketNotifiersMu sync.RWMutex
+	bucketNotifiers   = make(map[string]*notifier.BucketNotifier)
+	bucketNotifyCfgs  = make(map[string]bucketNotificationXML)
+)
+
+// eventMatches - true when eventName (e.g. "s3:ObjectCreated:Put")
+// satisfies a configured event pattern, which may end in ":*" as a
+// wildcard across every sub-event of that category.
+func eventMatches(pattern, eventName string) bool {
+	if pattern == eventName {
+		return true
+	}
+	if len(pattern) > 2 && pattern[len(pattern)-2:] == ":*" {
+		prefix := pattern[:len(pattern)-1] // keep trailing ':'
+		return len(eventName) > len(prefix) && eventName[:len(prefix)] == prefix
+	}
+	return false
+}
+
+// keyMatches - true when object satisfies every configured prefix/suffix
+// filter rule.
+func keyMatches(object string, rules []notificationFilterRule) bool {
+	for _, rule := range rules {
+		switch rule.Name {
+		case "prefix":
+			if len(object) < len(rule.Value) || object[:len(rule.Value)] != rule.Value {
+				return false
+			}
+		case "suffix":
+			if len(object) < len(rule.Value) || object[len(object)-len(rule.Value):] != rule.Value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// notifyObjectEvent - dispatches eventName for bucket/object to every
+// target configured on that bucket whose event pattern and key
+// filters match. Called through the notify* wrappers by the HTTP
+// handlers and the compose driver after a successful PutObject,
+// CompleteMultipartUpload, DeleteObject or CopyObject; the
+// fsObjects/xlObjects methods themselves do not call it.
+func notifyObjectEvent(eventName notifier.EventName, bucket, object string, size int64, etag string) {
+	bucketNotifiersMu.RLock()
+	n, ok := bucketNotifiers[bucket]
+	cfg := bucketNotifyCfgs[bucket]
+	bucketNotifiersMu.RUnlock()
+	if !ok {
+		return
+	}
+
+	for _, qc := range cfg.QueueConfigurations {
+		if qc.TargetID == "" || !keyMatches(object, qc.Filters) {
+			continue
+		}
+		for _, pattern := range qc.Events {
+			if eventMatches(pattern, string(eventName)) {
+				n.NotifyTarget(qc.TargetID, notifier.Event{
+					EventName: eventName,
+					Bucket:    bucket,
+					Object:    object,
+					Size:      size,
+					ETag:      etag,
+					Time:      UTCNow(),
+				})
+				break
+			}
+		}
+	}
+}
+
+// splitNotifyTargetID - splits a QueueConfiguration's Queue value of
+// the form "<kind>:<config-id>" (e.g. "webhook:1") into its target
+// kind and server-config section id.
+func splitNotifyTargetID(targetID string) (kind, id string) {
+	parts := strings.SplitN(targetID, ":", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// newNotifyTarget - builds the notifier.Target a QueueConfiguration's
+// target id refers to, by looking up its section in the server
+// config's notify.<kind> table and constructing the matching target.
+func newNotifyTarget(targetID string) (notifier.Target, error) {
+	kind, id := splitNotifyTargetID(targetID)
+	switch kind {
+	case "webhook":
+		cfg, ok := globalServerConfig.Notify.Webhook[id]
+		if !ok || !cfg.Enabled {
+			return nil, errNotifyTargetNotConfigured
+		}
+		return notifier.NewWebhookTarget(cfg)
+	case "amqp":
+		cfg, ok := globalServerConfig.Notify.AMQP[id]
+		if !ok || !cfg.Enabled {
+			return nil, errNotifyTargetNotConfigured
+		}
+		return notifier.NewAMQPTarget(cfg)
+	case "nats":
+		cfg, ok := globalServerConfig.Notify.NATS[id]
+		if !ok || !cfg.Enabled {
+			return nil, errNotifyTargetNotConfigured
+		}
+		return notifier.NewNATSTarget(cfg)
+	case "redis":
+		cfg, ok := globalServerConfig.Notify.Redis[id]
+		if !ok || !cfg.Enabled {
+			return nil, errNotifyTargetNotConfigured
+		}
+		return notifier.NewRedisTarget(cfg)
+	case "postgresql":
+		cfg, ok := globalServerConfig.Notify.PostgreSQL[id]
+		if !ok || !cfg.Enabled {
+			return nil, errNotifyTargetNotConfigured
+		}
+		return notifier.NewPostgreSQLTarget(cfg)
+	case "mysql":
+		cfg, ok := globalServerConfig.Notify.MySQL[id]
+		if !ok || !cfg.Enabled {
+			return nil, errNotifyTargetNotConfigured
+		}
+		return notifier.NewMySQLTarget(cfg)
+	case "elasticsearch":
+		cfg, ok := globalServerConfig.Notify.Elasticsearch[id]
+		if !ok || !cfg.Enabled {
+			return nil, errNotifyTargetNotConfigured
+		}
+		return notifier.NewElasticsearchTarget(cfg)
+	default:
+		return nil, errNotifyTargetNotConfigured
+	}
+}
+
+// registerBucketNotificationRouter - mounts the bucket notification
+// configuration handlers ahead of the plain object routes, matched on
+// the ?notification sub-resource the same way S3 distinguishes this
+// bucket-level request from an object PUT/GET of the same path shape.
+func registerBucketNotificationRouter(router *mux.Router, api objectAPIHandlers) {
+	router.Methods(http.MethodPut).Path("/{bucket}").Queries("notification", "").
+		HandlerFunc(api.PutBucketNotificationHandler)
+	router.Methods(http.MethodGet).Path("/{bucket}").Queries("notification", "").
+		HandlerFunc(api.GetBucketNotificationHandler)
+}
+
+// PutBucketNotificationHandler - PUT ?notification
+// Stores the notification configuration for a bucket and (re)builds
+// its BucketNotifier so subsequent writes start dispatching events.
+func (api objectAPIHandlers) PutBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	bucket := mux.Vars(r)["bucket"]
+
+	if apiErr := isReqAuthenticatedSTS(r); apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	var cfg bucketNotificationXML
+	if err := xml.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+
+	// Every QueueConfiguration must name a target that is actually
+	// configured (and enabled) in the server config before the
+	// configuration as a whole is accepted - a typo'd or disabled
+	// target id should not silently swallow every event sent to it.
+	targets := make(map[string]notifier.Target, len(cfg.QueueConfigurations))
+	for _, qc := range cfg.QueueConfigurations {
+		if _, ok := targets[qc.TargetID]; ok {
+			continue
+		}
+		target, err := newNotifyTarget(qc.TargetID)
+		if err != nil {
+			// Every target already built for an earlier entry in this
+			// configuration dialed a live connection (AMQP/NATS/SQL/...);
+			// none of them are kept, so none of them should be left open.
+			for _, built := range targets {
+				built.Close()
+			}
+			writeErrorResponse(w, ErrInvalidNotificationConfig, r.URL)
+			return
+		}
+		targets[qc.TargetID] = target
+	}
+
+	bucketNotifiersMu.Lock()
+	oldCfg := bucketNotifyCfgs[bucket]
+	bucketNotifyCfgs[bucket] = cfg
+	n, ok := bucketNotifiers[bucket]
+	if !ok {
+		n = notifier.NewBucketNotifier(globalNotificationSpillDir(bucket))
+		bucketNotifiers[bucket] = n
+	}
+	bucketNotifiersMu.Unlock()
+
+	for targetID, target := range targets {
+		n.SetTarget(targetID, target)
+	}
+
+	// A target id that was configured before this PUT but is not
+	// referenced by any QueueConfiguration in the new one is no
+	// longer reachable through NotifyTarget - stop dispatching to it
+	// and close its live connection rather than leaking it.
+	for _, qc := range oldCfg.QueueConfigurations {
+		if qc.TargetID == "" {
+			continue
+		}
+		if _, ok := targets[qc.TargetID]; !ok {
+			n.RemoveTarget(qc.TargetID)
+		}
+	}
+
+	writeSuccessResponseHeadersOnly(w)
+}
+
+// GetBucketNotificationHandler - GET ?notification
+// Returns the notification configuration currently set on a bucket,
+// or an empty NotificationConfiguration document if none is set.
+func (api objectAPIHandlers) GetBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	bucket := mux.Vars(r)["bucket"]
+
+	if apiErr := isReqAuthenticatedSTS(r); apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	bucketNotifiersMu.RLock()
+	cfg := bucketNotifyCfgs[bucket]
+	bucketNotifiersMu.RUnlock()
+
+	writeSuccessResponseXML(w, encodeXMLResponse(cfg))
+}
+
+// globalNotificationSpillDir - base directory used for a bucket's
+// notification target disk-spill overflow, rooted under the server's
+// configuration directory.
+func globalNotificationSpillDir(bucket string) string {
+	return filepath.Join(getConfigDir(), "notify-spill", bucket)
+}
@@ -17,8 +17,11 @@
 package cmd
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -37,6 +40,7 @@ const (
 var (
 	errInvalidAccessKeyLength = errors.New("Invalid access key, access key should be 5 to 20 characters in length")
 	errInvalidSecretKeyLength = errors.New("Invalid secret key, secret key should be 8 to 40 characters in length")
+	errExpiredCredential      = errors.New("Credential has expired")
 )
 var secretKeyMaxLen = secretKeyMaxLenAmazon
 
@@ -52,9 +56,10 @@ func isSecretKeyValid(secretKey string) bool {
 
 // credential container for access and secret keys.
 type credential struct {
-	AccessKey string    `xml:"AccessKeyId,omitempty" json:"accessKey,omitempty"`
-	SecretKey string    `xml:"SecretAccessKey,omitempty" json:"secretKey,omitempty"`
-	Expiry    time.Time `xml:"Expiration,omitempty" json:"expiry,omitempty"`
+	AccessKey    string    `xml:"AccessKeyId,omitempty" json:"accessKey,omitempty"`
+	SecretKey    string    `xml:"SecretAccessKey,omitempty" json:"secretKey,omitempty"`
+	SessionToken string    `xml:"SessionToken,omitempty" json:"sessionToken,omitempty"`
+	Expiry       time.Time `xml:"Expiration,omitempty" json:"expiry,omitempty"`
 
 	secretKeyHash []byte
 }
@@ -64,6 +69,36 @@ func (cred credential) IsValid() bool {
 	return isAccessKeyValid(cred.AccessKey) && isSecretKeyValid(cred.SecretKey)
 }
 
+// IsExpired - returns whether this is a temporary credential
+// (Expiry neither zero nor timeSentinel) that has already expired.
+func (cred credential) IsExpired() bool {
+	if cred.Expiry.IsZero() || cred.Expiry.Equal(timeSentinel) {
+		return false
+	}
+	return cred.Expiry.Before(time.Now().UTC())
+}
+
+// signSessionToken - signs accessKey+expiry with the root secret key so
+// that a session token handed back to a client can later be verified
+// without having to keep server-side session state around.
+func signSessionToken(accessKey string, expiry time.Time, secretKey string) string {
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte(accessKey))
+	mac.Write([]byte(expiry.UTC().Format(time.RFC3339)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// isValidSessionToken - verifies a session token presented by a client
+// (via the X-Amz-Security-Token header or query parameter) against the
+// credential it claims to belong to.
+func isValidSessionToken(cred credential, sessionToken string, rootSecretKey string) bool {
+	if cred.SessionToken == "" {
+		return sessionToken == ""
+	}
+	expected := signSessionToken(cred.AccessKey, cred.Expiry, rootSecretKey)
+	return hmac.Equal([]byte(expected), []byte(sessionToken))
+}
+
 // Equals - returns whether two credentials are equal or not.
 func (cred credential) Equal(ccred credential) bool {
 	if !ccred.IsValid() {
@@ -100,6 +135,12 @@ func createCredentialWithExpiry(accessKey, secretKey string, expiry time.Time) (
 	}
 	if !expiry.IsZero() {
 		cred.Expiry = expiry
+		// Temporary credentials carry a signed session token so that
+		// the caller can prove possession of this credential without
+		// the server having to remember it past a restart.
+		if !expiry.Equal(timeSentinel) {
+			cred.SessionToken = signSessionToken(accessKey, expiry, globalServerConfig.GetCredential().SecretKey)
+		}
 	}
 	return cred, err
 }
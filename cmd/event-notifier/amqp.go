@@ -0,0 +1,82 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notifier
+
+import (
+	"encoding/json"
+
+	"github.com/streadway/amqp"
+)
+
+// AMQPConfig - `notify.amqp.*` config file section.
+type AMQPConfig struct {
+	Config
+	URL          string
+	Exchange     string
+	RoutingKey   string
+	ExchangeType string
+	Mandatory    bool
+	Immediate    bool
+	Durable      bool
+	Internal     bool
+	NoWait       bool
+	AutoDeleted  bool
+}
+
+// AMQPTarget - publishes each event to a configured AMQP exchange.
+type AMQPTarget struct {
+	config AMQPConfig
+	conn   *amqp.Connection
+	ch     *amqp.Channel
+}
+
+// NewAMQPTarget - dials the broker, declares the configured exchange
+// and returns a ready to use target.
+func NewAMQPTarget(cfg AMQPConfig) (*AMQPTarget, error) {
+	conn, err := amqp.Dial(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err = ch.ExchangeDeclare(cfg.Exchange, cfg.ExchangeType, cfg.Durable,
+		cfg.AutoDeleted, cfg.Internal, cfg.NoWait, nil); err != nil {
+		ch.Close()
+		conn.Close()
+		return nil, err
+	}
+	return &AMQPTarget{config: cfg, conn: conn, ch: ch}, nil
+}
+
+// Send - implements Target.
+func (t *AMQPTarget) Send(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return t.ch.Publish(t.config.Exchange, t.config.RoutingKey, t.config.Mandatory, t.config.Immediate,
+		amqp.Publishing{ContentType: "application/json", Body: body})
+}
+
+// Close - implements Target.
+func (t *AMQPTarget) Close() error {
+	t.ch.Close()
+	return t.conn.Close()
+}
@@ -0,0 +1,68 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notifier
+
+import (
+	"gopkg.in/olivere/elastic.v5"
+)
+
+// ElasticsearchConfig - `notify.elasticsearch.*` config file section.
+type ElasticsearchConfig struct {
+	Config
+	URL   string
+	Index string
+}
+
+// ElasticsearchTarget - indexes each event as a document, keyed by
+// bucket/object so repeated events on the same key update in place
+// rather than accumulating duplicates.
+type ElasticsearchTarget struct {
+	config ElasticsearchConfig
+	client *elastic.Client
+}
+
+// NewElasticsearchTarget - connects to the cluster and ensures the
+// configured index exists.
+func NewElasticsearchTarget(cfg ElasticsearchConfig) (*ElasticsearchTarget, error) {
+	client, err := elastic.NewClient(elastic.SetURL(cfg.URL), elastic.SetSniff(false))
+	if err != nil {
+		return nil, err
+	}
+	exists, err := client.IndexExists(cfg.Index).Do(nil)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if _, err = client.CreateIndex(cfg.Index).Do(nil); err != nil {
+			return nil, err
+		}
+	}
+	return &ElasticsearchTarget{config: cfg, client: client}, nil
+}
+
+// Send - implements Target.
+func (t *ElasticsearchTarget) Send(event Event) error {
+	id := event.Bucket + "/" + event.Object
+	_, err := t.client.Index().Index(t.config.Index).Type("event").Id(id).BodyJson(event).Do(nil)
+	return err
+}
+
+// Close - implements Target.
+func (t *ElasticsearchTarget) Close() error {
+	t.client.Stop()
+	return nil
+}
@@ -0,0 +1,47 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notifier
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// droppedEvents - total number of notification events dropped across
+// all targets, labeled by target id, because neither the in-memory
+// queue nor its disk spill could accept them or because the target's
+// Send returned an error.
+var droppedEvents = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "minio",
+		Subsystem: "notify",
+		Name:      "dropped_events_total",
+		Help:      "Total number of bucket notification events dropped per target.",
+	},
+	[]string{"target_id"},
+)
+
+func init() {
+	prometheus.MustRegister(droppedEvents)
+}
+
+// RecordDropped - increments the dropped event counter for targetID.
+// Called by BucketNotifier whenever a TargetQueue reports a non-zero
+// delta in Dropped().
+func RecordDropped(targetID string, delta float64) {
+	if delta <= 0 {
+		return
+	}
+	droppedEvents.WithLabelValues(targetID).Add(delta)
+}
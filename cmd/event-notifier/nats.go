@@ -0,0 +1,61 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notifier
+
+import (
+	"encoding/json"
+
+	"github.com/nats-io/go-nats"
+)
+
+// NATSConfig - `notify.nats.*` config file section.
+type NATSConfig struct {
+	Config
+	Address string
+	Subject string
+}
+
+// NATSTarget - publishes each event to a configured NATS subject.
+type NATSTarget struct {
+	config NATSConfig
+	conn   *nats.Conn
+}
+
+// NewNATSTarget - connects to the NATS server and returns a ready to
+// use target.
+func NewNATSTarget(cfg NATSConfig) (*NATSTarget, error) {
+	conn, err := nats.Connect(cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	return &NATSTarget{config: cfg, conn: conn}, nil
+}
+
+// Send - implements Target.
+func (t *NATSTarget) Send(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return t.conn.Publish(t.config.Subject, body)
+}
+
+// Close - implements Target.
+func (t *NATSTarget) Close() error {
+	t.conn.Close()
+	return nil
+}
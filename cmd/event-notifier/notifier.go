@@ -0,0 +1,96 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notifier
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// BucketNotifier fans out events for a single bucket's notification
+// configuration to every target configured for it, via a bounded
+// TargetQueue per target so a slow target cannot block the caller.
+type BucketNotifier struct {
+	mu      sync.RWMutex
+	queues  map[string]*TargetQueue // keyed by target id
+	spillDir string
+}
+
+// NewBucketNotifier - spillDir is the base directory TargetQueue
+// overflow files are written under (one sub-directory per target).
+func NewBucketNotifier(spillDir string) *BucketNotifier {
+	return &BucketNotifier{
+		queues:   make(map[string]*TargetQueue),
+		spillDir: spillDir,
+	}
+}
+
+// SetTarget - (re)configures targetID to dispatch through target.
+// Replacing an existing target id closes the previous one first.
+func (n *BucketNotifier) SetTarget(targetID string, target Target) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if existing, ok := n.queues[targetID]; ok {
+		existing.Close()
+	}
+	n.queues[targetID] = NewTargetQueue(target, filepath.Join(n.spillDir, targetID))
+}
+
+// RemoveTarget - stops dispatching to targetID and closes it.
+func (n *BucketNotifier) RemoveTarget(targetID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if existing, ok := n.queues[targetID]; ok {
+		existing.Close()
+		delete(n.queues, targetID)
+	}
+}
+
+// NotifyTarget - enqueues event on the single target identified by
+// targetID. Safe to call on the hot write path: Enqueue never blocks
+// on the target itself. A targetID that is not configured on this
+// bucket is a silent no-op, matching a QueueConfiguration whose Queue
+// no longer exists.
+func (n *BucketNotifier) NotifyTarget(targetID string, event Event) {
+	n.mu.RLock()
+	q, ok := n.queues[targetID]
+	n.mu.RUnlock()
+	if !ok {
+		return
+	}
+	q.Enqueue(event)
+	RecordDropped(targetID, float64(q.DroppedDelta()))
+}
+
+// StartMetricsLoop - periodically samples each target queue's
+// dropped counter so RecordDropped stays current even between
+// NotifyTarget calls (e.g. while a target is down and the queue is
+// draining nothing).
+func (n *BucketNotifier) StartMetricsLoop(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			n.mu.RLock()
+			for id, q := range n.queues {
+				RecordDropped(id, float64(q.DroppedDelta()))
+			}
+			n.mu.RUnlock()
+		}
+	}()
+}
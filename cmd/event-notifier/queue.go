@@ -0,0 +1,179 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notifier
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// queueCapacity - number of events a target queue will buffer in
+// memory before spilling additional events to disk.
+const queueCapacity = 10000
+
+// spillDrainInterval - how often loop retries delivering events that
+// previously overflowed to disk, so a spilled event reaches target
+// once it recovers instead of waiting until the queue is closed.
+const spillDrainInterval = 30 * time.Second
+
+// TargetQueue - a bounded in-memory queue in front of a Target, so
+// that a single slow or unreachable consumer cannot block the object
+// layer write path. Once the in-memory buffer is full, further
+// events overflow to a spill file on disk; if the spill file itself
+// cannot be written the event is dropped and counted.
+type TargetQueue struct {
+	target Target
+
+	mu       sync.Mutex
+	buf      chan Event
+	spillDir string
+	done     chan struct{}
+
+	dropped      uint64
+	lastReported uint64
+}
+
+// NewTargetQueue - wraps target in a bounded queue backed by
+// spillDir for disk overflow, and starts the background goroutine
+// that drains it.
+func NewTargetQueue(target Target, spillDir string) *TargetQueue {
+	q := &TargetQueue{
+		target:   target,
+		buf:      make(chan Event, queueCapacity),
+		spillDir: spillDir,
+		done:     make(chan struct{}),
+	}
+	go q.loop()
+	return q
+}
+
+// Enqueue - queues event for delivery. Never blocks the caller for
+// longer than it takes to either push onto the in-memory channel or
+// append to the on-disk spill file.
+func (q *TargetQueue) Enqueue(event Event) {
+	select {
+	case q.buf <- event:
+	default:
+		if err := q.spill(event); err != nil {
+			atomic.AddUint64(&q.dropped, 1)
+		}
+	}
+}
+
+// Dropped - total number of events dropped by this queue: events
+// neither the in-memory buffer nor the disk spill could accept, plus
+// events whose target.Send returned an error.
+func (q *TargetQueue) Dropped() uint64 {
+	return atomic.LoadUint64(&q.dropped)
+}
+
+// DroppedDelta - number of additional events dropped since the last
+// call to DroppedDelta, as a running Prometheus-style counter should
+// be incremented - never the cumulative total, which would otherwise
+// be re-added on every call.
+func (q *TargetQueue) DroppedDelta() uint64 {
+	total := atomic.LoadUint64(&q.dropped)
+	last := atomic.SwapUint64(&q.lastReported, total)
+	if total < last {
+		// dropped only ever increases; this should not happen, but
+		// guard against underflow rather than report a bogus delta.
+		return 0
+	}
+	return total - last
+}
+
+// Close - stops draining and releases the underlying target. Waits
+// for loop to finish its final drain first, so target.Close() can
+// never run concurrently with a target.Send() call from loop.
+func (q *TargetQueue) Close() error {
+	close(q.buf)
+	<-q.done
+	return q.target.Close()
+}
+
+func (q *TargetQueue) loop() {
+	defer close(q.done)
+
+	ticker := time.NewTicker(spillDrainInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-q.buf:
+			if !ok {
+				// buf was closed by Close - replay anything still on
+				// disk one last time before handing back to Close.
+				q.drainSpill()
+				return
+			}
+			if err := q.target.Send(event); err != nil {
+				atomic.AddUint64(&q.dropped, 1)
+			}
+		case <-ticker.C:
+			// Periodically retry events that overflowed to disk while
+			// the in-memory buffer was full, instead of leaving them
+			// stranded until the queue is closed.
+			q.drainSpill()
+		}
+	}
+}
+
+func (q *TargetQueue) spill(event Event) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err := os.MkdirAll(q.spillDir, 0700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(q.spillDir, "event-")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gob.NewEncoder(f).Encode(event)
+}
+
+func (q *TargetQueue) drainSpill() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entries, err := ioutil.ReadDir(q.spillDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(q.spillDir, entry.Name())
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		var event Event
+		err = gob.NewDecoder(f).Decode(&event)
+		f.Close()
+		if err == nil {
+			if sendErr := q.target.Send(event); sendErr != nil {
+				atomic.AddUint64(&q.dropped, 1)
+			}
+		}
+		os.Remove(path)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notifier
+
+import (
+	"encoding/json"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// RedisConfig - `notify.redis.*` config file section.
+type RedisConfig struct {
+	Config
+	Address  string
+	Password string
+	Key      string
+}
+
+// RedisTarget - pushes each event, JSON encoded, onto a configured
+// Redis list via RPUSH.
+type RedisTarget struct {
+	config RedisConfig
+	pool   *redis.Pool
+}
+
+// NewRedisTarget - builds a connection pool for cfg.Address and
+// returns a ready to use target.
+func NewRedisTarget(cfg RedisConfig) (*RedisTarget, error) {
+	pool := &redis.Pool{
+		MaxIdle: 5,
+		Dial: func() (redis.Conn, error) {
+			conn, err := redis.Dial("tcp", cfg.Address)
+			if err != nil {
+				return nil, err
+			}
+			if cfg.Password != "" {
+				if _, err = conn.Do("AUTH", cfg.Password); err != nil {
+					conn.Close()
+					return nil, err
+				}
+			}
+			return conn, nil
+		},
+	}
+	// Fail fast if the server is unreachable rather than only
+	// discovering it on the first Send.
+	conn := pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("PING"); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &RedisTarget{config: cfg, pool: pool}, nil
+}
+
+// Send - implements Target.
+func (t *RedisTarget) Send(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	conn := t.pool.Get()
+	defer conn.Close()
+	_, err = conn.Do("RPUSH", t.config.Key, body)
+	return err
+}
+
+// Close - implements Target.
+func (t *RedisTarget) Close() error {
+	return t.pool.Close()
+}
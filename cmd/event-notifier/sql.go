@@ -0,0 +1,127 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notifier
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	// Registers the "postgres" and "mysql" database/sql drivers.
+	_ "github.com/lib/pq"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// PostgreSQLConfig - `notify.postgresql.*` config file section.
+type PostgreSQLConfig struct {
+	Config
+	ConnectionString string
+	Table            string
+}
+
+// MySQLConfig - `notify.mysql.*` config file section.
+type MySQLConfig struct {
+	Config
+	DSN   string
+	Table string
+}
+
+// sqlTarget - shared implementation behind PostgreSQLTarget and
+// MySQLTarget: both simply upsert a (key, event_name, payload) row per
+// event, keyed by "bucket/object", using database/sql, so there is
+// no reason to duplicate the Send/Close logic per driver.
+type sqlTarget struct {
+	db    *sql.DB
+	table string
+	// upsertQuery has three positional placeholders in driver
+	// syntax: key, event name and JSON payload.
+	upsertQuery string
+}
+
+func openSQLTarget(driverName, dataSourceName, table, upsertQuery string) (*sqlTarget, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqlTarget{db: db, table: table, upsertQuery: upsertQuery}, nil
+}
+
+func (t *sqlTarget) send(event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	key := event.Bucket + "/" + event.Object
+	_, err = t.db.Exec(t.upsertQuery, key, string(event.EventName), payload)
+	return err
+}
+
+func (t *sqlTarget) close() error {
+	return t.db.Close()
+}
+
+// PostgreSQLTarget - upserts each event into a configured PostgreSQL
+// table.
+type PostgreSQLTarget struct {
+	config PostgreSQLConfig
+	*sqlTarget
+}
+
+// NewPostgreSQLTarget - opens the connection and returns a ready to
+// use target.
+func NewPostgreSQLTarget(cfg PostgreSQLConfig) (*PostgreSQLTarget, error) {
+	query := `INSERT INTO ` + cfg.Table + ` (key, event_name, payload) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET event_name = $2, payload = $3`
+	base, err := openSQLTarget("postgres", cfg.ConnectionString, cfg.Table, query)
+	if err != nil {
+		return nil, err
+	}
+	return &PostgreSQLTarget{config: cfg, sqlTarget: base}, nil
+}
+
+// Send - implements Target.
+func (t *PostgreSQLTarget) Send(event Event) error { return t.send(event) }
+
+// Close - implements Target.
+func (t *PostgreSQLTarget) Close() error { return t.close() }
+
+// MySQLTarget - upserts each event into a configured MySQL table.
+type MySQLTarget struct {
+	config MySQLConfig
+	*sqlTarget
+}
+
+// NewMySQLTarget - opens the connection and returns a ready to use
+// target.
+func NewMySQLTarget(cfg MySQLConfig) (*MySQLTarget, error) {
+	query := `INSERT INTO ` + cfg.Table + ` (event_key, event_name, payload) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE event_name = VALUES(event_name), payload = VALUES(payload)`
+	base, err := openSQLTarget("mysql", cfg.DSN, cfg.Table, query)
+	if err != nil {
+		return nil, err
+	}
+	return &MySQLTarget{config: cfg, sqlTarget: base}, nil
+}
+
+// Send - implements Target.
+func (t *MySQLTarget) Send(event Event) error { return t.send(event) }
+
+// Close - implements Target.
+func (t *MySQLTarget) Close() error { return t.close() }
@@ -0,0 +1,76 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package notifier implements bucket event notifications: it turns
+// object layer events (ObjectCreated, ObjectRemoved, ObjectAccessed)
+// into S3-compatible notification records and dispatches them to a
+// set of pluggable, independently configurable targets.
+package notifier
+
+import "time"
+
+// EventName - one of the S3-compatible event names this package
+// knows how to emit.
+type EventName string
+
+const (
+	ObjectCreatedPut                 EventName = "s3:ObjectCreated:Put"
+	ObjectCreatedPost                EventName = "s3:ObjectCreated:Post"
+	ObjectCreatedCopy                EventName = "s3:ObjectCreated:Copy"
+	ObjectCreatedCompleteMultipartUpload EventName = "s3:ObjectCreated:CompleteMultipartUpload"
+	ObjectRemovedDelete              EventName = "s3:ObjectRemoved:Delete"
+	ObjectAccessedGet                EventName = "s3:ObjectAccessed:Get"
+	ObjectAccessedHead               EventName = "s3:ObjectAccessed:Head"
+)
+
+// Event - a single bucket notification record, modeled after the S3
+// notification JSON schema closely enough that Send implementations
+// can marshal it directly.
+type Event struct {
+	EventName EventName
+	Bucket    string
+	Object    string
+	Size      int64
+	ETag      string
+	Time      time.Time
+	// UserAgent/Host/RequestID are carried through for targets
+	// (e.g. Elasticsearch, PostgreSQL) that index them for search.
+	UserAgent string
+	Host      string
+	RequestID string
+}
+
+// Target - a destination bucket notifications can be dispatched to.
+// Implementations must be safe to call Send from multiple goroutines
+// concurrently; the queue in front of each target serializes writes
+// for implementations that are not.
+type Target interface {
+	// Send delivers a single event. A non-nil error causes the
+	// event to be counted as dropped by the owning queue rather
+	// than retried indefinitely.
+	Send(event Event) error
+	// Close releases any resources (connections, file handles)
+	// held by the target.
+	Close() error
+}
+
+// Config - identifies one configured target by its config-file
+// section id (e.g. "1" in `notify.amqp.1`), used for both routing
+// PutBucketNotification entries and for metrics labeling.
+type Config struct {
+	ID      string
+	Enabled bool
+}
@@ -0,0 +1,72 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// WebhookConfig - `notify.webhook.*` config file section.
+type WebhookConfig struct {
+	Config
+	Endpoint string
+}
+
+// WebhookTarget - posts each event as a JSON document to a configured
+// HTTP(S) endpoint.
+type WebhookTarget struct {
+	config WebhookConfig
+	client *http.Client
+}
+
+// NewWebhookTarget - validates cfg and returns a ready to use target.
+func NewWebhookTarget(cfg WebhookConfig) (*WebhookTarget, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("notifier: webhook endpoint is required")
+	}
+	return &WebhookTarget{
+		config: cfg,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+// Send - implements Target.
+func (t *WebhookTarget) Send(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Post(t.config.Endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.New("notifier: webhook target returned " + resp.Status)
+	}
+	return nil
+}
+
+// Close - implements Target. The webhook target holds no persistent
+// connection, so there is nothing to release.
+func (t *WebhookTarget) Close() error {
+	return nil
+}
@@ -0,0 +1,322 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// composeMinPartSize - every part but the last one in a
+	// composed multipart upload must be at least this large,
+	// matching the S3 multipart minimum part size.
+	composeMinPartSize = 5 * 1024 * 1024
+
+	// composeMaxParts - a composed object may not be built from
+	// more than this many source parts.
+	composeMaxParts = 10000
+
+	// composeMaxObjectSize - total size of a composed object may
+	// not exceed 5 TiB, matching the S3 maximum object size.
+	composeMaxObjectSize = 5 * 1024 * 1024 * 1024 * 1024
+)
+
+var (
+	errComposeTooManyParts    = errors.New("Object composition would require more than 10000 parts")
+	errComposeObjectTooLarge  = errors.New("Composed object size exceeds the 5 TiB maximum object size")
+	errComposeSourceNotFound  = errors.New("One or more of the specified compose sources does not exist")
+	errComposeSourceCondition = errors.New("One or more of the specified compose sources failed a match condition")
+)
+
+// ComposeSource - describes a single source contributing bytes to a
+// ComposeObject call, mirroring the information carried by an
+// x-amz-copy-source-N request header (or its JSON POST body
+// equivalent).
+type ComposeSource struct {
+	Bucket string
+	Object string
+
+	// Start/End are inclusive byte offsets into the source object.
+	// End == -1 means "through the end of the object".
+	Start int64
+	End   int64
+
+	// Conditional headers copied from x-amz-copy-source-N-if-*.
+	MatchETag         string
+	NoneMatchETag     string
+	MatchModifiedTime int64
+	UnmodifiedTime    int64
+}
+
+// length - number of bytes this source contributes, given the size
+// of the underlying object.
+func (c ComposeSource) length(srcSize int64) int64 {
+	end := c.End
+	if end < 0 || end >= srcSize {
+		end = srcSize - 1
+	}
+	return end - c.Start + 1
+}
+
+// planComposeStep describes how a single source should be copied
+// into the destination: either as a single-part server-side copy, or
+// as one copy-part of a multipart upload.
+type planComposeStep struct {
+	src        ComposeSource
+	partNumber int // 0 means "use CopyObject, not CopyObjectPart"
+}
+
+// planCompose - decides, for each source, whether the whole
+// composition can collapse to a single CopyObject call (exactly one
+// source covering the whole object, of any size) or whether it
+// must go through a multipart upload with one copy-part per source.
+//
+// Returns the ordered plan plus the total composed size, after
+// validating the 10,000 part and 5 TiB limits.
+func planCompose(srcs []ComposeSource, srcSizes []int64) ([]planComposeStep, int64, error) {
+	if len(srcs) == 0 {
+		return nil, 0, errors.New("Object composition requires at least one source")
+	}
+
+	var total int64
+	for i, src := range srcs {
+		total += src.length(srcSizes[i])
+	}
+	if total > composeMaxObjectSize {
+		return nil, 0, errComposeObjectTooLarge
+	}
+
+	// A single whole-object source with no range can be satisfied
+	// by a plain server-side copy - no multipart upload needed.
+	if len(srcs) == 1 && srcs[0].Start == 0 && (srcs[0].End < 0 || srcs[0].End == srcSizes[0]-1) {
+		return []planComposeStep{{src: srcs[0]}}, total, nil
+	}
+
+	if len(srcs) > composeMaxParts {
+		return nil, 0, errComposeTooManyParts
+	}
+
+	plan := make([]planComposeStep, len(srcs))
+	for i, src := range srcs {
+		partSize := src.length(srcSizes[i])
+		// Every part but the very last one must respect the
+		// multipart minimum part size.
+		if i != len(srcs)-1 && partSize < composeMinPartSize {
+			return nil, 0, fmt.Errorf("compose source %d/%s is %d bytes, below the %d byte minimum part size",
+				i+1, src.Object, partSize, composeMinPartSize)
+		}
+		plan[i] = planComposeStep{src: src, partNumber: i + 1}
+	}
+	return plan, total, nil
+}
+
+// checkComposeConditions - validates the per-source match/etag
+// conditions against the current ObjectInfo of that source, before
+// any part of the composition has started copying bytes.
+func checkComposeConditions(src ComposeSource, info ObjectInfo) error {
+	if src.MatchETag != "" && src.MatchETag != info.ETag {
+		return errComposeSourceCondition
+	}
+	if src.NoneMatchETag != "" && src.NoneMatchETag == info.ETag {
+		return errComposeSourceCondition
+	}
+	if src.MatchModifiedTime != 0 && info.ModTime.Unix() < src.MatchModifiedTime {
+		return errComposeSourceCondition
+	}
+	if src.UnmodifiedTime != 0 && info.ModTime.Unix() > src.UnmodifiedTime {
+		return errComposeSourceCondition
+	}
+	return nil
+}
+
+// composeSSEOptions - customer-provided keys needed to re-key a
+// composed object whenever a source is SSE-C encrypted and the
+// destination should be encrypted under a (possibly different)
+// customer key. A zero value means "no re-keying needed": sources
+// and destination are copied byte-for-byte as stored.
+type composeSSEOptions struct {
+	decrypt    bool
+	decryptKey SSECustomerKey
+	encrypt    bool
+	encryptKey SSECustomerKey
+}
+
+// errComposeSSEMultiSource - S3 does not support re-keying a compose
+// across more than one source; only a single whole-object source can
+// be decrypted and/or re-encrypted while composing.
+var errComposeSSEMultiSource = errors.New("Server Side Encryption re-keying is only supported for a single compose source")
+
+// composeObjectCommon - shared driver used by both fsObjects and
+// xlObjects ComposeObject implementations. It validates every source
+// up-front (so no partial multipart state is left behind on
+// failure), then either performs a single CopyObject or drives a
+// full multipart upload of copy-parts. When sseOpts requests
+// decryption and/or encryption, the single source is streamed
+// through DecryptRequest/EncryptRequest via GetObject/PutObject
+// instead of a raw server-side CopyObject.
+func composeObjectCommon(obj ObjectLayer, dstBucket, dstObject string, srcs []ComposeSource, sseOpts composeSSEOptions) (ObjectInfo, error) {
+	srcInfos := make([]ObjectInfo, len(srcs))
+	srcSizes := make([]int64, len(srcs))
+	for i, src := range srcs {
+		info, err := obj.GetObjectInfo(src.Bucket, src.Object)
+		if err != nil {
+			return ObjectInfo{}, errComposeSourceNotFound
+		}
+		if err = checkComposeConditions(src, info); err != nil {
+			return ObjectInfo{}, err
+		}
+		srcInfos[i] = info
+		srcSizes[i] = info.Size
+	}
+
+	plan, _, err := planCompose(srcs, srcSizes)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	// Re-keying is only possible when the plan collapsed to the
+	// single-source, whole-object CopyObject fast path below - a
+	// ranged single source still falls through to the multipart
+	// CopyObjectPart loop, which copies raw bytes and cannot re-key
+	// them. len(srcs) alone does not capture this: a single source
+	// with a byte range also produces a multipart plan.
+	if (sseOpts.decrypt || sseOpts.encrypt) && !(len(plan) == 1 && plan[0].partNumber == 0) {
+		return ObjectInfo{}, errComposeSSEMultiSource
+	}
+
+	// Single source, no range - a plain server side copy is
+	// sufficient and avoids the overhead of a multipart upload,
+	// unless a re-key was requested in which case the content has to
+	// be streamed through decrypt/encrypt rather than copied as-is.
+	if len(plan) == 1 && plan[0].partNumber == 0 {
+		src := plan[0].src
+		srcInfo := srcInfos[0]
+
+		if !sseOpts.decrypt && !sseOpts.encrypt {
+			info, err := obj.CopyObject(src.Bucket, src.Object, dstBucket, dstObject, srcInfo)
+			if err != nil {
+				return ObjectInfo{}, err
+			}
+			notifyCopyObject(dstBucket, dstObject, info)
+			return info, nil
+		}
+
+		info, err := composeReKeyedSource(obj, src, srcInfo, dstBucket, dstObject, sseOpts)
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+		notifyCopyObject(dstBucket, dstObject, info)
+		return info, nil
+	}
+
+	uploadID, err := obj.NewMultipartUpload(dstBucket, dstObject, srcInfos[0].UserDefined)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	for _, step := range plan {
+		startOffset := step.src.Start
+		length := step.src.length(srcSizesFor(srcs, srcSizes, step.src))
+		if _, err = obj.CopyObjectPart(step.src.Bucket, step.src.Object, dstBucket, dstObject,
+			uploadID, step.partNumber, startOffset, length); err != nil {
+			// Best-effort cleanup so we do not leave an
+			// orphaned multipart upload behind.
+			obj.AbortMultipartUpload(dstBucket, dstObject, uploadID)
+			return ObjectInfo{}, err
+		}
+	}
+
+	info, err := obj.CompleteMultipartUpload(dstBucket, dstObject, uploadID, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	notifyCompleteMultipartUpload(dstBucket, dstObject, info)
+	return info, nil
+}
+
+// composeReKeyedSource - streams the single source through
+// DecryptRequest (if it was SSE-C encrypted under decryptKey) and/or
+// EncryptRequest (if the destination should be SSE-C encrypted under
+// encryptKey), since a customer-key re-key cannot be satisfied by a
+// raw server-side CopyObject.
+func composeReKeyedSource(obj ObjectLayer, src ComposeSource, srcInfo ObjectInfo, dstBucket, dstObject string, sseOpts composeSSEOptions) (ObjectInfo, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(obj.GetObject(src.Bucket, src.Object, 0, srcInfo.Size, pw))
+	}()
+	defer pr.Close()
+
+	metadata := make(map[string]string, len(srcInfo.UserDefined))
+	for k, v := range srcInfo.UserDefined {
+		metadata[k] = v
+	}
+
+	var body io.Reader = pr
+	var err error
+	if sseOpts.decrypt {
+		if body, err = DecryptRequest(body, sseOpts.decryptKey, 0, metadata); err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+	if sseOpts.encrypt {
+		if body, err = EncryptRequest(body, sseOpts.encryptKey, metadata); err != nil {
+			return ObjectInfo{}, err
+		}
+	} else {
+		// The source's own SSE-C metadata describes ciphertext that
+		// no longer exists once body has been decrypted above (or
+		// never existed, if the source wasn't encrypted to begin
+		// with) - carrying it into an unencrypted destination's
+		// metadata would make IsEncrypted() report the new object as
+		// encrypted forever, so a plain GET fails and a GET with any
+		// customer key tries to AES-GCM-open plaintext.
+		delete(metadata, metaSSECustomerAlgorithm)
+		delete(metadata, metaSSEIV)
+		delete(metadata, metaSSESealedKey)
+	}
+	return obj.PutObject(dstBucket, dstObject, -1, body, metadata)
+}
+
+// srcSizesFor - looks up the pre-fetched size for a given source,
+// used while iterating the compose plan.
+func srcSizesFor(srcs []ComposeSource, sizes []int64, target ComposeSource) int64 {
+	for i, src := range srcs {
+		if src == target {
+			return sizes[i]
+		}
+	}
+	return 0
+}
+
+// ComposeObject - creates dstObject by concatenating up to 10,000
+// source objects (or byte ranges thereof) server side, using a
+// single CopyObject when possible and falling back to a multipart
+// upload of copy-parts otherwise. sseOpts requests re-keying through
+// DecryptRequest/EncryptRequest instead, when a source and/or the
+// destination is SSE-C encrypted.
+func (fs fsObjects) ComposeObject(dstBucket, dstObject string, srcs []ComposeSource, sseOpts composeSSEOptions) (ObjectInfo, error) {
+	return composeObjectCommon(fs, dstBucket, dstObject, srcs, sseOpts)
+}
+
+// ComposeObject - see fsObjects.ComposeObject. The XL implementation
+// shares the same driver; erasure coding of the resulting parts is
+// handled transparently by CopyObject/CopyObjectPart.
+func (xl xlObjects) ComposeObject(dstBucket, dstObject string, srcs []ComposeSource, sseOpts composeSSEOptions) (ObjectInfo, error) {
+	return composeObjectCommon(xl, dstBucket, dstObject, srcs, sseOpts)
+}
@@ -0,0 +1,77 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"io"
+
+	"github.com/minio/minio/pkg/policy"
+)
+
+// ObjectLayer - every backend (single-disk FS, single erasure set XL,
+// multi-set XL) this server can run against implements this
+// interface; every object API handler is written against it rather
+// than against a concrete backend type.
+type ObjectLayer interface {
+	// Bucket operations.
+	MakeBucket(bucket string) error
+	GetBucketInfo(bucket string) (BucketInfo, error)
+	ListBuckets() ([]BucketInfo, error)
+	DeleteBucket(bucket string) error
+
+	// Object operations.
+	GetObject(bucket, object string, startOffset, length int64, writer io.Writer) error
+	GetObjectInfo(bucket, object string) (ObjectInfo, error)
+	PutObject(bucket, object string, size int64, data io.Reader, metadata map[string]string) (ObjectInfo, error)
+	DeleteObject(bucket, object string) error
+	CopyObject(srcBucket, srcObject, dstBucket, dstObject string, srcInfo ObjectInfo) (ObjectInfo, error)
+	ComposeObject(dstBucket, dstObject string, srcs []ComposeSource, sseOpts composeSSEOptions) (ObjectInfo, error)
+	ListObjects(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error)
+
+	// Multipart operations.
+	NewMultipartUpload(bucket, object string, metadata map[string]string) (string, error)
+	PutObjectPart(bucket, object, uploadID string, partNumber int, size int64, data io.Reader) (PartInfo, error)
+	CopyObjectPart(srcBucket, srcObject, dstBucket, dstObject, uploadID string, partNumber int, startOffset, length int64) (PartInfo, error)
+	ListObjectParts(bucket, object, uploadID string, partNumberMarker, maxParts int) (ListPartsInfo, error)
+	AbortMultipartUpload(bucket, object, uploadID string) error
+	CompleteMultipartUpload(bucket, object, uploadID string, parts []CompletePart) (ObjectInfo, error)
+	ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarker, delimiter string, maxUploads int) (ListMultipartsInfo, error)
+
+	// Server/backend lifecycle and introspection.
+	Shutdown() error
+	StorageInfo() StorageInfo
+
+	// Healing - background/administrative repair of the backend,
+	// driven by the admin API rather than regular object traffic.
+	HealBucket(bucket string) error
+	HealObject(bucket, object string) error
+	ListObjectsHeal(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error)
+	ListUploadsHeal(bucket, prefix, keyMarker, uploadIDMarker, delimiter string, maxUploads int) (ListMultipartsInfo, error)
+
+	// Anonymous (unauthenticated, policy-gated) access, used by the
+	// handlers when a request carries no credentials at all but the
+	// bucket policy may still permit it.
+	AnonGetObject(bucket, object string, startOffset, length int64, writer io.Writer) error
+	AnonGetObjectInfo(bucket, object string) (ObjectInfo, error)
+	AnonPutObject(bucket, object string, size int64, data io.Reader, metadata map[string]string) (ObjectInfo, error)
+	AnonListObjects(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error)
+
+	// Bucket policy.
+	SetBucketPolicy(bucket string, policy policy.BucketAccessPolicy) error
+	GetBucketPolicy(bucket string) (policy.BucketAccessPolicy, error)
+	DeleteBucketPolicy(bucket string) error
+}
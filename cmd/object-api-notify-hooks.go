@@ -0,0 +1,47 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import notifier "github.com/minio/minio/cmd/event-notifier"
+
+// notifyPutObject, notifyCompleteMultipartUpload, notifyDeleteObject,
+// notifyCopyObject - thin wrappers around notifyObjectEvent, one per
+// event this server emits on a successful PutObject,
+// CompleteMultipartUpload, DeleteObject or CopyObject, so the dispatch
+// call site reads the same way regardless of which event it is
+// reporting.
+//
+// These are invoked from object-handlers.go, post-policy-handlers.go
+// and composeObjectCommon right after the corresponding ObjectLayer
+// call succeeds. The fsObjects/xlObjects methods do not emit events
+// themselves, so an ObjectLayer call made outside those paths is not
+// reported to any notification target.
+func notifyPutObject(bucket, object string, info ObjectInfo) {
+	notifyObjectEvent(notifier.ObjectCreatedPut, bucket, object, info.Size, info.ETag)
+}
+
+func notifyCompleteMultipartUpload(bucket, object string, info ObjectInfo) {
+	notifyObjectEvent(notifier.ObjectCreatedCompleteMultipartUpload, bucket, object, info.Size, info.ETag)
+}
+
+func notifyDeleteObject(bucket, object string) {
+	notifyObjectEvent(notifier.ObjectRemovedDelete, bucket, object, 0, "")
+}
+
+func notifyCopyObject(bucket, object string, info ObjectInfo) {
+	notifyObjectEvent(notifier.ObjectCreatedCopy, bucket, object, info.Size, info.ETag)
+}
@@ -0,0 +1,233 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// composeSourceHeaderPrefix - a compose request is distinguished from
+// a regular CopyObject request by carrying more than one
+// x-amz-copy-source-N header instead of a single x-amz-copy-source.
+const composeSourceHeaderPrefix = "X-Amz-Copy-Source-"
+
+// composeXMLSource - a single <Source> entry in a ?compose POST body.
+type composeXMLSource struct {
+	Bucket        string `xml:"Bucket"`
+	Object        string `xml:"Object"`
+	Range         string `xml:"Range,omitempty"`
+	IfMatch       string `xml:"IfMatch,omitempty"`
+	IfNoneMatch   string `xml:"IfNoneMatch,omitempty"`
+}
+
+// composeXMLBody - the ?compose POST body listing every source to be
+// concatenated into the destination object.
+type composeXMLBody struct {
+	XMLName xml.Name           `xml:"ComposeObject"`
+	Sources []composeXMLSource `xml:"Source"`
+}
+
+// registerComposeObjectRouter - mounts ComposeObjectHandler ahead of
+// the regular PutObject/CopyObject routes, guarded by
+// isComposeObjectRequest so that a plain single-source CopyObject
+// request still falls through to the existing CopyObject handler.
+func registerComposeObjectRouter(router *mux.Router, api objectAPIHandlers) {
+	router.NewRoute().PathPrefix("/{bucket}/{object:.+}").
+		MatcherFunc(func(r *http.Request, rm *mux.RouteMatch) bool {
+			return isComposeObjectRequest(r)
+		}).
+		HandlerFunc(api.ComposeObjectHandler)
+}
+
+// isComposeObjectRequest - a request is a composition request when
+// it carries more than one x-amz-copy-source-N header, or when it is
+// a POST to the ?compose sub-resource.
+func isComposeObjectRequest(r *http.Request) bool {
+	if _, ok := r.URL.Query()["compose"]; ok && r.Method == http.MethodPost {
+		return true
+	}
+	count := 0
+	for header := range r.Header {
+		if !strings.HasPrefix(header, composeSourceHeaderPrefix) {
+			continue
+		}
+		// Only X-Amz-Copy-Source-<N> itself counts, not its -Range/-If-*
+		// companions or the copy-source SSE-C headers.
+		if _, err := strconv.Atoi(strings.TrimPrefix(header, composeSourceHeaderPrefix)); err == nil {
+			count++
+			if count > 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseComposeRange - parses a "bytes=start-end" range header value
+// into inclusive start/end offsets. Returns start=0, end=-1 (meaning
+// "whole object") when no range is given.
+func parseComposeRange(rangeHeader string) (start, end int64, err error) {
+	if rangeHeader == "" {
+		return 0, -1, nil
+	}
+	rangeHeader = strings.TrimPrefix(rangeHeader, "bytes=")
+	parts := strings.SplitN(rangeHeader, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, errInvalidRange
+	}
+	if start, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+		return 0, 0, errInvalidRange
+	}
+	if parts[1] == "" {
+		return start, -1, nil
+	}
+	if end, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return 0, 0, errInvalidRange
+	}
+	return start, end, nil
+}
+
+// composeSourcesFromHeaders - collects every X-Amz-Copy-Source-N
+// header (and its optional -Range/-If-Match/-If-None-Match
+// companions) present on the request, in ascending N order.
+func composeSourcesFromHeaders(r *http.Request) ([]ComposeSource, APIErrorCode) {
+	var srcs []ComposeSource
+	for n := 1; n <= composeMaxParts; n++ {
+		suffix := strconv.Itoa(n)
+		copySource := r.Header.Get(composeSourceHeaderPrefix + suffix)
+		if copySource == "" {
+			break
+		}
+		bucket, object, err := path2BucketObject(copySource)
+		if err != nil {
+			return nil, ErrInvalidCopySource
+		}
+		start, end, err := parseComposeRange(r.Header.Get(composeSourceHeaderPrefix + suffix + "-Range"))
+		if err != nil {
+			return nil, ErrInvalidCopySource
+		}
+		srcs = append(srcs, ComposeSource{
+			Bucket:        bucket,
+			Object:        object,
+			Start:         start,
+			End:           end,
+			MatchETag:     r.Header.Get(composeSourceHeaderPrefix + suffix + "-If-Match"),
+			NoneMatchETag: r.Header.Get(composeSourceHeaderPrefix + suffix + "-If-None-Match"),
+		})
+	}
+	return srcs, ErrNone
+}
+
+// composeSourcesFromBody - parses the ?compose POST body into the
+// ComposeSource slice used by the object layer.
+func composeSourcesFromBody(r *http.Request) ([]ComposeSource, APIErrorCode) {
+	var body composeXMLBody
+	if err := xml.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, ErrMalformedXML
+	}
+	srcs := make([]ComposeSource, len(body.Sources))
+	for i, s := range body.Sources {
+		start, end, err := parseComposeRange(s.Range)
+		if err != nil {
+			return nil, ErrInvalidCopySource
+		}
+		srcs[i] = ComposeSource{
+			Bucket:        s.Bucket,
+			Object:        s.Object,
+			Start:         start,
+			End:           end,
+			MatchETag:     s.IfMatch,
+			NoneMatchETag: s.IfNoneMatch,
+		}
+	}
+	return srcs, ErrNone
+}
+
+// ComposeObjectHandler - handles both header-driven
+// (x-amz-copy-source-N) and body-driven (?compose) object
+// composition requests.
+func (api objectAPIHandlers) ComposeObjectHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	dstBucket := vars["bucket"]
+	dstObject := vars["object"]
+
+	// isReqAuthenticatedSTS verifies the v4 request signature against
+	// the credential resolveRequestCredential resolves - root or a
+	// still-valid STS temporary credential - the same check every
+	// other object handler runs; resolveRequestCredential alone only
+	// confirms the access key exists and isn't expired, it never
+	// proves the caller actually holds the matching secret key.
+	if apiErr := isReqAuthenticatedSTS(r); apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+
+	// A request composing from an SSE-C encrypted source must carry
+	// a matching x-amz-copy-source-server-side-encryption-customer-*
+	// key, and a request composing into an SSE-C encrypted
+	// destination must carry the regular trio - both validated up
+	// front alongside the other compose conditions, then threaded
+	// through to ComposeObject so it can re-key instead of relying
+	// on a raw server-side copy.
+	copySourceKey, decrypt, err := ParseSSECopySourceRequest(r)
+	if err != nil {
+		writeErrorResponse(w, sseErrToAPIErrCode(err), r.URL)
+		return
+	}
+	destKey, encrypt, apiErr := validateSSECPutRequest(r)
+	if apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+	sseOpts := composeSSEOptions{
+		decrypt:    decrypt,
+		decryptKey: copySourceKey,
+		encrypt:    encrypt,
+		encryptKey: destKey,
+	}
+
+	var srcs []ComposeSource
+	if _, ok := r.URL.Query()["compose"]; ok {
+		srcs, apiErr = composeSourcesFromBody(r)
+	} else {
+		srcs, apiErr = composeSourcesFromHeaders(r)
+	}
+	if apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	objInfo, err := objectAPI.ComposeObject(dstBucket, dstObject, srcs, sseOpts)
+	if err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	response := generateCopyObjectResponse(objInfo.ETag, objInfo.ModTime)
+	writeResponse(w, http.StatusOK, encodeXMLResponse(response), mimeXML)
+}
@@ -0,0 +1,407 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// registerObjectRouter - mounts the plain bucket/object handlers:
+// everything a request falls through to once it has failed every
+// more specific matcher (ComposeObject, PostPolicy, bucket
+// notification configuration) registered ahead of it.
+func registerObjectRouter(router *mux.Router, api objectAPIHandlers) {
+	router.Methods(http.MethodPost).Path("/{bucket}/{object:.+}").Queries("uploadId", "").
+		HandlerFunc(api.CompleteMultipartUploadHandler)
+	router.Methods(http.MethodPut).Path("/{bucket}/{object:.+}").
+		HeadersRegexp("X-Amz-Copy-Source", ".+").
+		HandlerFunc(api.CopyObjectHandler)
+	router.Methods(http.MethodPut).Path("/{bucket}/{object:.+}").HandlerFunc(api.PutObjectHandler)
+	router.Methods(http.MethodGet).Path("/{bucket}/{object:.+}").HandlerFunc(api.GetObjectHandler)
+	router.Methods(http.MethodDelete).Path("/{bucket}/{object:.+}").HandlerFunc(api.DeleteObjectHandler)
+}
+
+// completeMultipartUploadXML - the CompleteMultipartUpload request
+// body: the ordered list of parts the client uploaded, each named by
+// PartNumber and the ETag PutObjectPart returned for it.
+type completeMultipartUploadXML struct {
+	XMLName xml.Name       `xml:"CompleteMultipartUpload"`
+	Parts   []CompletePart `xml:"Part"`
+}
+
+// completeMultipartUploadResponse - matches the shape of the S3
+// CompleteMultipartUploadResult document.
+type completeMultipartUploadResponse struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CompleteMultipartUploadResult"`
+	Bucket  string
+	Key     string
+	ETag    string
+}
+
+// CompleteMultipartUploadHandler - POST /bucket/object?uploadId=...
+// Assembles the uploaded parts into the final object. This is the
+// only caller of objectAPI.CompleteMultipartUpload for a normal
+// (non-compose) multipart upload, so it - not just ComposeObject's
+// internal multipart driver - must fire the
+// s3:ObjectCreated:CompleteMultipartUpload event for that common
+// large-object-upload case to be observable by notification targets.
+func (api objectAPIHandlers) CompleteMultipartUploadHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+	uploadID := r.URL.Query().Get("uploadId")
+
+	if apiErr := isReqAuthenticatedSTS(r); apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	var completeReq completeMultipartUploadXML
+	if err := xml.NewDecoder(r.Body).Decode(&completeReq); err != nil {
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+
+	objInfo, err := objectAPI.CompleteMultipartUpload(bucket, object, uploadID, completeReq.Parts)
+	if err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	notifyCompleteMultipartUpload(bucket, object, objInfo)
+
+	response := completeMultipartUploadResponse{Bucket: bucket, Key: object, ETag: objInfo.ETag}
+	writeResponse(w, http.StatusOK, encodeXMLResponse(response), mimeXML)
+}
+
+// PutObjectHandler - PUT /bucket/object
+// Stores the request body as object, transparently encrypting it
+// on the way in when the request carries the SSE-C customer-key trio.
+func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	if apiErr := isReqAuthenticatedSTS(r); apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	metadata := extractMetadataFromHeader(r.Header)
+
+	sseKey, encrypt, apiErr := validateSSECPutRequest(r)
+	if apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+
+	body := r.Body
+	size := r.ContentLength
+	var reader = io.Reader(body)
+	if encrypt {
+		var err error
+		reader, err = EncryptRequest(body, sseKey, metadata)
+		if err != nil {
+			writeErrorResponse(w, sseErrToAPIErrCode(err), r.URL)
+			return
+		}
+		// The encrypted stream carries the chunk framing overhead on
+		// top of the plaintext size, so the caller's Content-Length no
+		// longer describes how many bytes PutObject will read.
+		size = -1
+	}
+
+	objInfo, err := objectAPI.PutObject(bucket, object, size, reader, metadata)
+	if err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	notifyPutObject(bucket, object, objInfo)
+
+	w.Header().Set("ETag", "\""+objInfo.ETag+"\"")
+	writeSuccessResponseHeadersOnly(w)
+}
+
+// parseRequestRange - parses a single-range "bytes=start-end" Range
+// header (RFC 7233), clamped to an object of the given size. Returns
+// ranged=false, with start/length covering the whole object, when the
+// request carries no Range header at all. A Range header naming more
+// than one range is treated the same way, since this server does not
+// support multipart/byteranges responses.
+func parseRequestRange(r *http.Request, size int64) (start, length int64, ranged bool, apiErr APIErrorCode) {
+	rangeHeader := r.Header.Get("Range")
+	if rangeHeader == "" {
+		return 0, size, false, ErrNone
+	}
+	spec := strings.TrimPrefix(rangeHeader, "bytes=")
+	if spec == rangeHeader || strings.Contains(spec, ",") {
+		return 0, size, false, ErrNone
+	}
+
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, false, ErrInvalidRange
+	}
+
+	switch {
+	case parts[0] == "" && parts[1] == "":
+		return 0, 0, false, ErrInvalidRange
+	case parts[0] == "":
+		// "bytes=-N" - the last N bytes of the object.
+		suffixLength, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || suffixLength <= 0 {
+			return 0, 0, false, ErrInvalidRange
+		}
+		if suffixLength > size {
+			suffixLength = size
+		}
+		return size - suffixLength, suffixLength, true, ErrNone
+	case parts[1] == "":
+		// "bytes=N-" - from N to the end of the object.
+		start, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || start < 0 || start >= size {
+			return 0, 0, false, ErrInvalidRange
+		}
+		return start, size - start, true, ErrNone
+	default:
+		start, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || start < 0 || start >= size {
+			return 0, 0, false, ErrInvalidRange
+		}
+		end, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, ErrInvalidRange
+		}
+		if end >= size {
+			end = size - 1
+		}
+		return start, end - start + 1, true, ErrNone
+	}
+}
+
+// setRangeResponseHeaders - overrides the Content-Length
+// setObjectHeaders set for the whole object and, for an actual Range
+// request, adds the Content-Range header and switches the response to
+// 206 Partial Content. Must be called after setObjectHeaders and
+// before the response body is written.
+func setRangeResponseHeaders(w http.ResponseWriter, start, length, size int64, ranged bool) {
+	w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	if !ranged {
+		return
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, size))
+	w.WriteHeader(http.StatusPartialContent)
+}
+
+// GetObjectHandler - GET /bucket/object
+// Streams object to the client, honoring a Range request header and
+// transparently decrypting it on the way out when it was stored under
+// SSE-C and the request carries a matching customer key.
+func (api objectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	if apiErr := isReqAuthenticatedSTS(r); apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	objInfo, err := objectAPI.GetObjectInfo(bucket, object)
+	if err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	sseKey, apiErr := validateSSECGetRequest(r, objInfo.UserDefined)
+	if apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+
+	// objInfo.Size is the sealed (ciphertext) size for an encrypted
+	// object, not the plaintext size a client's Range header and the
+	// response Content-Length/Content-Range must be expressed in.
+	clientSize := objInfo.Size
+	if IsEncrypted(objInfo.UserDefined) {
+		clientSize = ssePlaintextSize(objInfo.Size)
+	}
+
+	startOffset, length, ranged, apiErr := parseRequestRange(r, clientSize)
+	if apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+
+	if !IsEncrypted(objInfo.UserDefined) {
+		w.Header().Set("ETag", "\""+objInfo.ETag+"\"")
+		setObjectHeaders(w, objInfo)
+		setRangeResponseHeaders(w, startOffset, length, clientSize, ranged)
+		if err = objectAPI.GetObject(bucket, object, startOffset, length, w); err != nil {
+			writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		}
+		return
+	}
+
+	// The ciphertext read must start at the owning sealed chunk, not
+	// at startOffset itself - each sseChunkSize plaintext chunk is
+	// individually GCM sealed, so seeking mid-chunk in storage would
+	// both misalign the ciphertext framing and be rejected by the tag
+	// check. DecryptRequest derives the correct per-chunk nonce for
+	// that chunk index from startOffset and discards the leading
+	// plaintext bytes within it on its own.
+	chunkIndex := startOffset / sseChunkSize
+	cipherStart := chunkIndex * sseChunkSealedSize
+	cipherLength := objInfo.Size - cipherStart
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(objectAPI.GetObject(bucket, object, cipherStart, cipherLength, pw))
+	}()
+	defer pr.Close()
+
+	decrypted, err := DecryptRequest(pr, sseKey, startOffset, objInfo.UserDefined)
+	if err != nil {
+		writeErrorResponse(w, sseErrToAPIErrCode(err), r.URL)
+		return
+	}
+
+	w.Header().Set("ETag", "\""+objInfo.ETag+"\"")
+	setObjectHeaders(w, objInfo)
+	setRangeResponseHeaders(w, startOffset, length, clientSize, ranged)
+	if _, err := io.CopyN(w, decrypted, length); err != nil {
+		// Headers and a partial body are already on the wire by the
+		// time a mid-stream decrypt/storage error can surface here,
+		// so there is no response left to send - just surface it in
+		// the server log the way other unrecoverable-after-the-fact
+		// errors in this package do.
+		errorIf(err, "Unable to stream decrypted object to client")
+	}
+}
+
+// DeleteObjectHandler - DELETE /bucket/object
+func (api objectAPIHandlers) DeleteObjectHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	if apiErr := isReqAuthenticatedSTS(r); apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	if err := objectAPI.DeleteObject(bucket, object); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	notifyDeleteObject(bucket, object)
+
+	writeSuccessNoContent(w)
+}
+
+// CopyObjectHandler - PUT /bucket/object with an X-Amz-Copy-Source
+// header - a single-source, whole-object server side copy. Shares
+// its SSE-C re-keying semantics with ComposeObject: a source and/or
+// destination customer key is threaded through DecryptRequest/
+// EncryptRequest instead of a raw copy whenever either is set.
+func (api objectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	dstBucket := vars["bucket"]
+	dstObject := vars["object"]
+
+	if apiErr := isReqAuthenticatedSTS(r); apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+
+	srcBucket, srcObject, err := path2BucketObject(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		writeErrorResponse(w, ErrInvalidCopySource, r.URL)
+		return
+	}
+
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	copySourceKey, decrypt, err := ParseSSECopySourceRequest(r)
+	if err != nil {
+		writeErrorResponse(w, sseErrToAPIErrCode(err), r.URL)
+		return
+	}
+	destKey, encrypt, apiErr := validateSSECPutRequest(r)
+	if apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+
+	srcInfo, err := objectAPI.GetObjectInfo(srcBucket, srcObject)
+	if err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	var objInfo ObjectInfo
+	if !decrypt && !encrypt {
+		objInfo, err = objectAPI.CopyObject(srcBucket, srcObject, dstBucket, dstObject, srcInfo)
+	} else {
+		objInfo, err = composeReKeyedSource(objectAPI,
+			ComposeSource{Bucket: srcBucket, Object: srcObject, Start: 0, End: -1}, srcInfo,
+			dstBucket, dstObject,
+			composeSSEOptions{decrypt: decrypt, decryptKey: copySourceKey, encrypt: encrypt, encryptKey: destKey})
+	}
+	if err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	notifyCopyObject(dstBucket, dstObject, objInfo)
+
+	response := generateCopyObjectResponse(objInfo.ETag, objInfo.ModTime)
+	writeResponse(w, http.StatusOK, encodeXMLResponse(response), mimeXML)
+}
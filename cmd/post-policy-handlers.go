@@ -0,0 +1,228 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxPostPolicyFormMemory - multipart form fields are tiny; only the
+// file part is allowed to stream, so a small in-memory threshold is
+// enough before ParseMultipartForm starts spilling to temp files.
+const maxPostPolicyFormMemory = 1 << 20 // 1 MiB
+
+// registerPostPolicyRouter - mounts PostPolicyHandler ahead of the
+// plain object routes, matched the same way S3 distinguishes a
+// browser form upload from any other bucket-level request: a POST
+// carrying a multipart/form-data body directly to the bucket path.
+func registerPostPolicyRouter(router *mux.Router, api objectAPIHandlers) {
+	router.Methods(http.MethodPost).Path("/{bucket}").
+		HeadersRegexp("Content-Type", "multipart/form-data*").
+		HandlerFunc(api.PostPolicyHandler)
+}
+
+// PostPolicyHandler - POST /bucket
+// Handles a browser-based upload authorized by a presigned POST
+// policy: parses the multipart form, verifies the v4 signature over
+// the policy document, enforces every condition in the policy, and
+// on success streams the file field into PutObject.
+func (api objectAPIHandlers) PostPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	bucket := mux.Vars(r)["bucket"]
+
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	if err := r.ParseMultipartForm(maxPostPolicyFormMemory); err != nil {
+		writeErrorResponse(w, ErrMalformedPOSTRequest, r.URL)
+		return
+	}
+
+	formValues := make(map[string]string)
+	for key, values := range r.MultipartForm.Value {
+		if len(values) > 0 {
+			formValues[strings.ToLower(key)] = values[0]
+		}
+	}
+
+	policyB64 := formValues["policy"]
+	if policyB64 == "" {
+		writeErrorResponse(w, ErrMalformedPOSTRequest, r.URL)
+		return
+	}
+	policy, err := parsePostPolicy(policyB64)
+	if err != nil {
+		writeErrorResponse(w, ErrMalformedPOSTRequest, r.URL)
+		return
+	}
+	if err = checkPolicyExpiry(policy); err != nil {
+		writeErrorResponse(w, ErrPolicyAlreadyExpired, r.URL)
+		return
+	}
+
+	if apiErr := verifyPostPolicySignature(formValues, policyB64); apiErr != ErrNone {
+		writeErrorResponse(w, apiErr, r.URL)
+		return
+	}
+
+	fileHeaders, ok := r.MultipartForm.File["file"]
+	if !ok || len(fileHeaders) == 0 {
+		writeErrorResponse(w, ErrMalformedPOSTRequest, r.URL)
+		return
+	}
+	fileHeader := fileHeaders[0]
+	file, err := fileHeader.Open()
+	if err != nil {
+		writeErrorResponse(w, ErrInternalError, r.URL)
+		return
+	}
+	defer file.Close()
+
+	if err = checkPolicyConditions(policy, formValues, fileHeader.Size); err != nil {
+		writeErrorResponse(w, toPostPolicyAPIErrorCode(err), r.URL)
+		return
+	}
+
+	object := substituteFilenamePlaceholder(formValues["key"], fileHeader.Filename)
+	metadata := extractPostPolicyMetadata(formValues)
+
+	// Browser uploads carry the SSE-C trio as regular form fields
+	// rather than headers, since the browser has no opportunity to
+	// set custom headers on a plain HTML form POST.
+	sseKey, encrypt, err := parseSSECustomerHeaders(
+		formValues["x-amz-server-side-encryption-customer-algorithm"],
+		formValues["x-amz-server-side-encryption-customer-key"],
+		formValues["x-amz-server-side-encryption-customer-key-md5"])
+	if err != nil {
+		writeErrorResponse(w, sseErrToAPIErrCode(err), r.URL)
+		return
+	}
+
+	var body io.Reader = file
+	if encrypt {
+		body, err = EncryptRequest(file, sseKey, metadata)
+		if err != nil {
+			writeErrorResponse(w, ErrInternalError, r.URL)
+			return
+		}
+	}
+
+	objInfo, err := objectAPI.PutObject(bucket, object, -1, body, metadata)
+	if err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	notifyPutObject(bucket, object, objInfo)
+
+	if redirectURL := formValues["success_action_redirect"]; redirectURL != "" {
+		u, err := url.Parse(redirectURL)
+		if err == nil {
+			q := u.Query()
+			q.Set("bucket", bucket)
+			q.Set("key", object)
+			q.Set("etag", objInfo.ETag)
+			u.RawQuery = q.Encode()
+			http.Redirect(w, r, u.String(), http.StatusSeeOther)
+			return
+		}
+	}
+
+	writeSuccessNoContent(w)
+}
+
+// verifyPostPolicySignature - resolves x-amz-credential to a known
+// access key and verifies x-amz-signature was computed over the
+// base64 policy document using that credential's secret key, the
+// same way a v4 signed request is verified. The credential may be
+// either the root credential or a temporary one minted by the STS
+// endpoints - scoping a browser upload to a short-lived STS
+// credential is the main reason to use a POST policy at all, so
+// rejecting every STS-signed policy with ErrInvalidAccessKeyID would
+// defeat that use case entirely.
+func verifyPostPolicySignature(formValues map[string]string, policyB64 string) APIErrorCode {
+	accessKey, _, err := parseCredentialHeader(formValues["x-amz-credential"])
+	if err != nil {
+		return ErrInvalidAccessKeyID
+	}
+	cred := globalServerConfig.GetCredential()
+	if accessKey != cred.AccessKey {
+		stsCred, ok := globalSTSTempCreds.get(accessKey)
+		if !ok {
+			return ErrInvalidAccessKeyID
+		}
+		cred = stsCred
+	}
+	if cred.IsExpired() {
+		return ErrExpiredPresignRequest
+	}
+
+	date, err := time.Parse(iso8601Format, formValues["x-amz-date"])
+	if err != nil {
+		return ErrMalformedDate
+	}
+
+	signingKey := getSigningKey(cred.SecretKey, date, formValues["x-amz-credential"])
+	expectedSignature := getSignature(signingKey, policyB64)
+
+	if !compareSignatureV4(expectedSignature, formValues["x-amz-signature"]) {
+		return ErrSignatureDoesNotMatch
+	}
+	return ErrNone
+}
+
+// substituteFilenamePlaceholder - replaces a literal "${filename}"
+// in key with the name of the uploaded file, matching the behavior
+// S3 documents for the POST policy `key` form field.
+func substituteFilenamePlaceholder(key, filename string) string {
+	return strings.Replace(key, "${filename}", filename, -1)
+}
+
+// extractPostPolicyMetadata - pulls Content-Type and every
+// x-amz-meta-* form field into the metadata map PutObject expects.
+func extractPostPolicyMetadata(formValues map[string]string) map[string]string {
+	metadata := make(map[string]string)
+	for key, value := range formValues {
+		switch {
+		case key == "content-type":
+			metadata["content-type"] = value
+		case strings.HasPrefix(key, "x-amz-meta-"):
+			metadata[key] = value
+		}
+	}
+	return metadata
+}
+
+// toPostPolicyAPIErrorCode - maps a policy validation error to the
+// S3 API error code returned to the client.
+func toPostPolicyAPIErrorCode(err error) APIErrorCode {
+	switch err {
+	case errPolicyConditionFailed:
+		return ErrPostPolicyConditionInvalid
+	case errPolicyExpired:
+		return ErrPolicyAlreadyExpired
+	default:
+		return ErrMalformedPOSTRequest
+	}
+}
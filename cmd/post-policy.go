@@ -0,0 +1,183 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	errPolicyMissingFields    = errors.New("Policy document is missing required fields")
+	errPolicyExpired          = errors.New("Policy document has expired")
+	errPolicyConditionFailed  = errors.New("Policy document condition failed")
+	errPolicyInvalidCondition = errors.New("Policy document contains an invalid condition")
+)
+
+// postPolicyCondition - a single entry of a policy document's
+// `conditions` array, after being normalized out of the three JSON
+// shapes S3 accepts: ["eq", "$key", "value"], ["starts-with",
+// "$key", "value"] and {"key": "value"}.
+type postPolicyCondition struct {
+	matchType string // "eq", "starts-with" or "content-length-range"
+	key       string
+	value     string
+	min, max  int64
+}
+
+// postPolicy - a parsed POST policy document (the base64-decoded,
+// JSON "policy" form field of a browser upload).
+type postPolicy struct {
+	Expiration time.Time
+	Conditions []postPolicyCondition
+}
+
+// parsePostPolicy - decodes and unmarshals the base64 "policy" form
+// field into a postPolicy, normalizing every condition into
+// postPolicyCondition.
+func parsePostPolicy(policyB64 string) (postPolicy, error) {
+	raw, err := base64.StdEncoding.DecodeString(policyB64)
+	if err != nil {
+		return postPolicy{}, err
+	}
+
+	var doc struct {
+		Expiration string        `json:"expiration"`
+		Conditions []interface{} `json:"conditions"`
+	}
+	if err = json.Unmarshal(raw, &doc); err != nil {
+		return postPolicy{}, err
+	}
+	if doc.Expiration == "" {
+		return postPolicy{}, errPolicyMissingFields
+	}
+	expiry, err := time.Parse(time.RFC3339, doc.Expiration)
+	if err != nil {
+		return postPolicy{}, err
+	}
+
+	policy := postPolicy{Expiration: expiry}
+	for _, raw := range doc.Conditions {
+		cond, err := normalizePostPolicyCondition(raw)
+		if err != nil {
+			return postPolicy{}, err
+		}
+		policy.Conditions = append(policy.Conditions, cond)
+	}
+	return policy, nil
+}
+
+// normalizePostPolicyCondition - converts one raw JSON condition
+// entry into a postPolicyCondition, accepting both the
+// ["op", "$field", "value"] array form and the {"field": "value"}
+// exact-match shorthand form.
+func normalizePostPolicyCondition(raw interface{}) (postPolicyCondition, error) {
+	switch v := raw.(type) {
+	case []interface{}:
+		if len(v) != 3 {
+			return postPolicyCondition{}, errPolicyInvalidCondition
+		}
+		op, ok1 := v[0].(string)
+		if !ok1 {
+			return postPolicyCondition{}, errPolicyInvalidCondition
+		}
+
+		// content-length-range carries two numbers, not a "$key"
+		// field, so it must be handled before the generic
+		// string-key cast below ever sees v[1].
+		if op == "content-length-range" {
+			min, ok1 := toInt64(v[1])
+			max, ok2 := toInt64(v[2])
+			if !ok1 || !ok2 {
+				return postPolicyCondition{}, errPolicyInvalidCondition
+			}
+			return postPolicyCondition{matchType: "content-length-range", min: min, max: max}, nil
+		}
+
+		key, ok2 := v[1].(string)
+		if !ok2 {
+			return postPolicyCondition{}, errPolicyInvalidCondition
+		}
+		key = strings.TrimPrefix(key, "$")
+
+		value, ok3 := v[2].(string)
+		if !ok3 || (op != "eq" && op != "starts-with") {
+			return postPolicyCondition{}, errPolicyInvalidCondition
+		}
+		return postPolicyCondition{matchType: op, key: key, value: value}, nil
+
+	case map[string]interface{}:
+		for key, value := range v {
+			strValue, ok := value.(string)
+			if !ok {
+				return postPolicyCondition{}, errPolicyInvalidCondition
+			}
+			return postPolicyCondition{matchType: "eq", key: key, value: strValue}, nil
+		}
+		return postPolicyCondition{}, errPolicyInvalidCondition
+
+	default:
+		return postPolicyCondition{}, errPolicyInvalidCondition
+	}
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int64(n), true
+	case int64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// checkPolicyExpiry - rejects a policy whose expiration timestamp has
+// already passed.
+func checkPolicyExpiry(policy postPolicy) error {
+	if policy.Expiration.Before(time.Now().UTC()) {
+		return errPolicyExpired
+	}
+	return nil
+}
+
+// checkPolicyConditions - validates every `eq`/`starts-with` form
+// field condition and the `content-length-range` condition against
+// the values actually submitted in the multipart form and the size
+// of the uploaded body.
+func checkPolicyConditions(policy postPolicy, formValues map[string]string, contentLength int64) error {
+	for _, cond := range policy.Conditions {
+		switch cond.matchType {
+		case "eq":
+			if formValues[cond.key] != cond.value {
+				return errPolicyConditionFailed
+			}
+		case "starts-with":
+			if !strings.HasPrefix(formValues[cond.key], cond.value) {
+				return errPolicyConditionFailed
+			}
+		case "content-length-range":
+			if contentLength < cond.min || contentLength > cond.max {
+				return errPolicyConditionFailed
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+// encodePolicy - base64 encodes a POST policy document JSON body for
+// use as the "policy" form field in the tests below.
+func encodePolicy(doc string) string {
+	return base64.StdEncoding.EncodeToString([]byte(doc))
+}
+
+func TestParsePostPolicyExpiration(t *testing.T) {
+	future := time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+	past := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+
+	testCases := []struct {
+		expiration string
+		shouldFail bool
+	}{
+		{future, false},
+		{past, true},
+	}
+
+	for i, testCase := range testCases {
+		doc := `{"expiration": "` + testCase.expiration + `", "conditions": []}`
+		policy, err := parsePostPolicy(encodePolicy(doc))
+		if err != nil {
+			t.Fatalf("Test %d: unexpected parse error %s", i+1, err)
+		}
+		err = checkPolicyExpiry(policy)
+		if testCase.shouldFail && err != errPolicyExpired {
+			t.Errorf("Test %d: expected errPolicyExpired, got %v", i+1, err)
+		}
+		if !testCase.shouldFail && err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i+1, err)
+		}
+	}
+}
+
+func TestCheckPolicyConditionsStartsWith(t *testing.T) {
+	doc := `{
+		"expiration": "` + time.Now().UTC().Add(time.Hour).Format(time.RFC3339) + `",
+		"conditions": [
+			["starts-with", "$key", "uploads/"]
+		]
+	}`
+	policy, err := parsePostPolicy(encodePolicy(doc))
+	if err != nil {
+		t.Fatalf("Unexpected parse error %s", err)
+	}
+
+	testCases := []struct {
+		key        string
+		shouldPass bool
+	}{
+		{"uploads/picture.jpg", true},
+		{"other/picture.jpg", false},
+	}
+
+	for i, testCase := range testCases {
+		err := checkPolicyConditions(policy, map[string]string{"key": testCase.key}, 0)
+		if testCase.shouldPass && err != nil {
+			t.Errorf("Test %d: expected condition to pass, got %v", i+1, err)
+		}
+		if !testCase.shouldPass && err != errPolicyConditionFailed {
+			t.Errorf("Test %d: expected errPolicyConditionFailed, got %v", i+1, err)
+		}
+	}
+}
+
+func TestCheckPolicyConditionsContentLengthRange(t *testing.T) {
+	doc := `{
+		"expiration": "` + time.Now().UTC().Add(time.Hour).Format(time.RFC3339) + `",
+		"conditions": [
+			["content-length-range", 10, 100]
+		]
+	}`
+	policy, err := parsePostPolicy(encodePolicy(doc))
+	if err != nil {
+		t.Fatalf("Unexpected parse error %s", err)
+	}
+
+	testCases := []struct {
+		size       int64
+		shouldPass bool
+	}{
+		{50, true},
+		{10, true},
+		{100, true},
+		{9, false},
+		{101, false},
+	}
+
+	for i, testCase := range testCases {
+		err := checkPolicyConditions(policy, map[string]string{}, testCase.size)
+		if testCase.shouldPass && err != nil {
+			t.Errorf("Test %d: expected size %d to pass, got %v", i+1, testCase.size, err)
+		}
+		if !testCase.shouldPass && err != errPolicyConditionFailed {
+			t.Errorf("Test %d: expected size %d to fail with errPolicyConditionFailed, got %v", i+1, testCase.size, err)
+		}
+	}
+}
+
+func TestVerifyPostPolicySignatureMismatch(t *testing.T) {
+	cred := globalServerConfig.GetCredential()
+	date := time.Now().UTC()
+
+	doc := `{"expiration": "` + date.Add(time.Hour).Format(time.RFC3339) + `", "conditions": []}`
+	policyB64 := encodePolicy(doc)
+
+	credentialHeader := cred.AccessKey + "/" + date.Format(yyyymmdd) + "/us-east-1/s3/aws4_request"
+	signingKey := getSigningKey(cred.SecretKey, date, credentialHeader)
+	validSignature := getSignature(signingKey, policyB64)
+
+	testCases := []struct {
+		signature  string
+		shouldPass bool
+	}{
+		{validSignature, true},
+		{"deadbeef", false},
+	}
+
+	for i, testCase := range testCases {
+		formValues := map[string]string{
+			"x-amz-credential": credentialHeader,
+			"x-amz-date":       date.Format(iso8601Format),
+			"x-amz-signature":  testCase.signature,
+		}
+		apiErr := verifyPostPolicySignature(formValues, policyB64)
+		if testCase.shouldPass && apiErr != ErrNone {
+			t.Errorf("Test %d: expected signature to verify, got %v", i+1, apiErr)
+		}
+		if !testCase.shouldPass && apiErr != ErrSignatureDoesNotMatch {
+			t.Errorf("Test %d: expected ErrSignatureDoesNotMatch, got %v", i+1, apiErr)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/gorilla/mux"
+)
+
+// objectAPIHandlers - receiver every S3 API handler hangs off of.
+// ObjectAPI is a func rather than a plain ObjectLayer field so that a
+// handler always sees the current object layer even if it was
+// (re)initialized after the router was built - there is exactly one
+// objectAPIHandlers value for the lifetime of the server.
+type objectAPIHandlers struct {
+	ObjectAPI func() ObjectLayer
+}
+
+// newObjectLayerFn/globalObjectAPI - set once by the server startup
+// path once the backend has finished initializing; objectAPIHandlers
+// built before that point still resolve to the live object layer
+// because ObjectAPI is a closure over this variable rather than a
+// value captured at router-build time.
+var globalObjectAPI ObjectLayer
+
+func newObjectLayerFn() ObjectLayer {
+	return globalObjectAPI
+}
+
+// configureServerHandler - builds the single *mux.Router every API
+// request is served through. This is the router returned to
+// net/http.Server by the startup path - every sub-router registered
+// here (and nowhere else) is actually reachable by a client.
+func configureServerHandler(endpoints EndpointList) (*mux.Router, error) {
+	api := objectAPIHandlers{ObjectAPI: newObjectLayerFn}
+
+	router := mux.NewRouter().SkipClean(true)
+	registerAPIRouter(router, api)
+	return router, nil
+}
+
+// registerAPIRouter - mounts every handler this server exposes onto
+// router. Each sub-router is registered here, and only here, so that
+// a route actually being reachable by a client is never dependent on
+// anything but this one function.
+func registerAPIRouter(router *mux.Router, api objectAPIHandlers) {
+	startSTSSubsystem(router, api)
+
+	registerComposeObjectRouter(router, api)
+	registerPostPolicyRouter(router, api)
+	registerBucketNotificationRouter(router, api)
+	registerObjectRouter(router, api)
+}
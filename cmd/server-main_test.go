@@ -20,6 +20,7 @@ import (
 	"errors"
 	"reflect"
 	"runtime"
+	"strconv"
 	"testing"
 )
 
@@ -167,6 +168,22 @@ func TestCheckSufficientDisks(t *testing.T) {
 			"/mnt/backend17",
 		}
 	}
+	// genBackendDisks returns n synthetic disk paths, following the
+	// same "/mnt/backendN" (or Windows "C:\mnt\backendN") naming
+	// xlDisks above uses, for set sizes too large to spell out by
+	// hand.
+	genBackendDisks := func(n int) []string {
+		disks := make([]string, n)
+		for i := 0; i < n; i++ {
+			if runtime.GOOS == globalWindowsOSName {
+				disks[i] = "C:\\mnt\\backend" + strconv.Itoa(i+1)
+			} else {
+				disks[i] = "/mnt/backend" + strconv.Itoa(i+1)
+			}
+		}
+		return disks
+	}
+
 	// List of test cases fo sufficient disk verification.
 	testCases := []struct {
 		disks       []string
@@ -187,21 +204,49 @@ func TestCheckSufficientDisks(t *testing.T) {
 			xlDisks[0:16],
 			nil,
 		},
-		// Larger than maximum number of disks > 16.
+		// Larger than maximum number of disks > 16, does not
+		// decompose into a supported erasure set width.
 		{
 			xlDisks,
 			errXLMaxDisks,
 		},
-		// Lesser than minimum number of disks < 6.
+		// Lesser than minimum erasure set size of 4.
 		{
 			xlDisks[0:3],
 			errXLMinDisks,
 		},
-		// Odd number of disks, not divisible by '2'.
+		// Odd number of disks, not a multiple of a supported set size.
 		{
 			append(xlDisks[0:10], xlDisks[11]),
 			errXLNumDisks,
 		},
+		// 32 disks - two erasure sets of 16.
+		{
+			genBackendDisks(32),
+			nil,
+		},
+		// 48 disks - three erasure sets of 16.
+		{
+			genBackendDisks(48),
+			nil,
+		},
+		// 64 disks - four erasure sets of 16.
+		{
+			genBackendDisks(64),
+			nil,
+		},
+		// 36 disks - does not divide evenly by 16 or 14, settles on
+		// three erasure sets of 12.
+		{
+			genBackendDisks(36),
+			nil,
+		},
+		// 44 disks - does not divide evenly by any set size above 4,
+		// settles on eleven erasure sets of 4.
+		{
+			genBackendDisks(44),
+			nil,
+		},
 	}
 
 	// Validates different variations of input disks.
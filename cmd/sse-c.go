@@ -0,0 +1,381 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// SSE-C request headers, and their x-amz-copy-source-server-side-
+// encryption-customer-* counterparts used when the source of a
+// copy/compose is itself SSE-C encrypted.
+const (
+	sseCustomerAlgorithm = "X-Amz-Server-Side-Encryption-Customer-Algorithm"
+	sseCustomerKey       = "X-Amz-Server-Side-Encryption-Customer-Key"
+	sseCustomerKeyMD5    = "X-Amz-Server-Side-Encryption-Customer-Key-MD5"
+
+	sseCopySourceCustomerAlgorithm = "X-Amz-Copy-Source-Server-Side-Encryption-Customer-Algorithm"
+	sseCopySourceCustomerKey       = "X-Amz-Copy-Source-Server-Side-Encryption-Customer-Key"
+	sseCopySourceCustomerKeyMD5    = "X-Amz-Copy-Source-Server-Side-Encryption-Customer-Key-MD5"
+
+	sseCustomerAlgorithmAES256 = "AES256"
+
+	// Object metadata keys used to persist just enough state to
+	// decrypt later, without ever storing the customer key itself.
+	metaSSECustomerAlgorithm = "X-Minio-Internal-Server-Side-Encryption-Customer-Algorithm"
+	metaSSEIV                = "X-Minio-Internal-Server-Side-Encryption-Iv"
+	metaSSESealedKey         = "X-Minio-Internal-Server-Side-Encryption-Sealed-Key"
+)
+
+var (
+	errSSECustomerAlgorithmNotSupported = errors.New("Requested Server Side Encryption Algorithm is not supported")
+	errSSECustomerKeyMissing            = errors.New("Server Side Encryption Customer Key is missing")
+	errSSECustomerKeyMD5Mismatch        = errors.New("Server Side Encryption Customer Key MD5 does not match")
+	errSSECustomerKeyInvalid            = errors.New("Server Side Encryption Customer Key is invalid")
+	errObjectNotEncrypted               = errors.New("Object is not encrypted, cannot be decrypted with a customer key")
+	errObjectEncrypted                  = errors.New("Object is encrypted, a Server Side Encryption Customer Key is required to read it")
+)
+
+// SSECustomerKey - a validated SSE-C customer key extracted from a
+// request, along with the raw bytes needed to derive a
+// data-encryption-key.
+type SSECustomerKey struct {
+	Algorithm string
+	Key       []byte // decoded, raw 32-byte AES-256 key
+}
+
+// parseSSECustomerHeaders - validates the trio of SSE-C values taken
+// from request headers or POST form fields. Returns a zero
+// SSECustomerKey (ok=false) when none of the values are present at
+// all, and an error when they are present but invalid.
+func parseSSECustomerHeaders(algorithm, key, keyMD5 string) (SSECustomerKey, bool, error) {
+	if algorithm == "" && key == "" && keyMD5 == "" {
+		return SSECustomerKey{}, false, nil
+	}
+	if algorithm != sseCustomerAlgorithmAES256 {
+		return SSECustomerKey{}, true, errSSECustomerAlgorithmNotSupported
+	}
+	if key == "" {
+		return SSECustomerKey{}, true, errSSECustomerKeyMissing
+	}
+
+	rawKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(rawKey) != 32 {
+		return SSECustomerKey{}, true, errSSECustomerKeyInvalid
+	}
+
+	sum := md5.Sum(rawKey)
+	if base64.StdEncoding.EncodeToString(sum[:]) != keyMD5 {
+		return SSECustomerKey{}, true, errSSECustomerKeyMD5Mismatch
+	}
+
+	return SSECustomerKey{Algorithm: algorithm, Key: rawKey}, true, nil
+}
+
+// ParseSSECustomerRequest - extracts and validates the SSE-C headers
+// carried by a PUT/GET request.
+func ParseSSECustomerRequest(r *http.Request) (SSECustomerKey, bool, error) {
+	return parseSSECustomerHeaders(
+		r.Header.Get(sseCustomerAlgorithm),
+		r.Header.Get(sseCustomerKey),
+		r.Header.Get(sseCustomerKeyMD5),
+	)
+}
+
+// ParseSSECopySourceRequest - extracts and validates the
+// x-amz-copy-source-server-side-encryption-customer-* headers used
+// to decrypt the source of a copy/compose.
+func ParseSSECopySourceRequest(r *http.Request) (SSECustomerKey, bool, error) {
+	return parseSSECustomerHeaders(
+		r.Header.Get(sseCopySourceCustomerAlgorithm),
+		r.Header.Get(sseCopySourceCustomerKey),
+		r.Header.Get(sseCopySourceCustomerKeyMD5),
+	)
+}
+
+// IsEncrypted - an object is considered SSE-C encrypted when it
+// carries the sealed data-encryption-key metadata written by
+// EncryptRequest below.
+func IsEncrypted(metadata map[string]string) bool {
+	_, ok := metadata[metaSSESealedKey]
+	return ok
+}
+
+// sealDEK - wraps (encrypts) the random per-object data-encryption-key
+// with the customer's key using AES-GCM, so only someone presenting
+// the same customer key can ever recover it.
+func sealDEK(customerKey, dek []byte) ([]byte, []byte, error) {
+	block, err := aes.NewCipher(customerKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, err
+	}
+	sealed := gcm.Seal(nil, nonce, dek, nil)
+	return sealed, nonce, nil
+}
+
+// unsealDEK - reverses sealDEK, recovering the object's
+// data-encryption-key given the customer key and the sealing nonce.
+func unsealDEK(customerKey, sealed, nonce []byte) ([]byte, error) {
+	block, err := aes.NewCipher(customerKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, errSSECustomerKeyInvalid
+	}
+	return dek, nil
+}
+
+// sseChunkSize - content is encrypted in fixed-size plaintext chunks,
+// each individually AES-GCM sealed, so that a bit flipped anywhere in
+// the stored ciphertext is detected on read rather than silently
+// turning into corrupted plaintext. This is the AES-GCM alternative
+// the request allows in place of AES-CTR + HMAC.
+const sseChunkSize = 64 * 1024
+
+// sseChunkSealedSize - the size of one sseChunkSize plaintext chunk
+// once AES-GCM sealed: the ciphertext is the same length as the
+// plaintext, plus the 16-byte GCM authentication tag. Translating a
+// plaintext byte range into the sealed chunk(s) that must be read
+// from storage - rather than reading the whole object and discarding
+// everything outside the range - depends on this, not just sseChunkSize.
+const sseChunkSealedSize = sseChunkSize + 16
+
+// ssePlaintextSize - recovers the plaintext size of an SSE-C encrypted
+// object from its stored (sealed/ciphertext) size. ObjectInfo.Size for
+// an encrypted object is the size of the sealed stream actually
+// written to storage, which carries a 16-byte GCM tag on top of every
+// sseChunkSize plaintext chunk - callers that need to honor a client's
+// Range header or report a Content-Length must convert back to the
+// plaintext size first, not operate on the sealed size directly.
+func ssePlaintextSize(sealedSize int64) int64 {
+	if sealedSize == 0 {
+		return 0
+	}
+	numChunks := (sealedSize + sseChunkSealedSize - 1) / sseChunkSealedSize
+	return sealedSize - numChunks*16
+}
+
+// chunkNonce - derives the per-chunk GCM nonce from the object's base
+// IV and the chunk index, so chunks never reuse a nonce under the
+// same data-encryption-key.
+func chunkNonce(baseNonce []byte, index uint64) []byte {
+	nonce := make([]byte, len(baseNonce))
+	copy(nonce, baseNonce)
+	for i := 0; i < 8 && i < len(nonce); i++ {
+		nonce[len(nonce)-1-i] ^= byte(index >> uint(8*i))
+	}
+	return nonce
+}
+
+// sseEncryptReader - streams plaintext out of src in sseChunkSize
+// pieces, sealing each with AES-GCM before handing ciphertext back to
+// the caller.
+type sseEncryptReader struct {
+	src        io.Reader
+	gcm        cipher.AEAD
+	baseNonce  []byte
+	chunkIndex uint64
+	pending    []byte
+	eof        bool
+}
+
+func (r *sseEncryptReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		plain := make([]byte, sseChunkSize)
+		n, err := io.ReadFull(r.src, plain)
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		if err == io.ErrUnexpectedEOF {
+			r.eof = true
+		}
+		nonce := chunkNonce(r.baseNonce, r.chunkIndex)
+		r.chunkIndex++
+		r.pending = r.gcm.Seal(nil, nonce, plain[:n], nil)
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// sseDecryptReader - reverses sseEncryptReader: reads sealed chunks
+// of sseChunkSize+gcm.Overhead() bytes from src, verifies and opens
+// each, and streams the resulting plaintext back to the caller. A
+// failed GCM tag check - meaning the stored ciphertext was corrupted
+// or tampered with - is surfaced as an error instead of silently
+// returning garbage plaintext.
+type sseDecryptReader struct {
+	src        io.Reader
+	gcm        cipher.AEAD
+	baseNonce  []byte
+	chunkIndex uint64
+	pending    []byte
+	eof        bool
+}
+
+func (r *sseDecryptReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		sealed := make([]byte, sseChunkSize+r.gcm.Overhead())
+		n, err := io.ReadFull(r.src, sealed)
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		if err == io.ErrUnexpectedEOF {
+			r.eof = true
+		}
+		nonce := chunkNonce(r.baseNonce, r.chunkIndex)
+		r.chunkIndex++
+		plain, openErr := r.gcm.Open(nil, nonce, sealed[:n], nil)
+		if openErr != nil {
+			return 0, errSSECustomerKeyInvalid
+		}
+		r.pending = plain
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// EncryptRequest - wraps content in a chunked, per-chunk
+// authenticated AES-GCM stream keyed by a fresh random
+// data-encryption-key, and records the base nonce plus the
+// customer-key-sealed data-encryption-key in metadata so the object
+// can be decrypted later without ever persisting the customer key
+// itself.
+func EncryptRequest(content io.Reader, sseKey SSECustomerKey, metadata map[string]string) (io.Reader, error) {
+	dek := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(dek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	baseNonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, baseNonce); err != nil {
+		return nil, err
+	}
+
+	sealed, keyNonce, err := sealDEK(sseKey.Key, dek)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata[metaSSECustomerAlgorithm] = sseKey.Algorithm
+	metadata[metaSSEIV] = base64.StdEncoding.EncodeToString(baseNonce)
+	metadata[metaSSESealedKey] = base64.StdEncoding.EncodeToString(append(keyNonce, sealed...))
+
+	return &sseEncryptReader{src: content, gcm: gcm, baseNonce: baseNonce}, nil
+}
+
+// DecryptRequest - reverses EncryptRequest: recovers the
+// data-encryption-key from metadata using the supplied customer key,
+// then wraps content in a matching chunked AES-GCM stream. offset is
+// the plaintext byte offset the caller wants content to start at -
+// callers must have already seeked the underlying storage read to
+// the first byte of the sealed chunk containing offset (chunkIndex *
+// (sseChunkSize + gcm.Overhead())); DecryptRequest derives the
+// correct per-chunk nonce for that chunk index and discards the
+// leading bytes of plaintext within it.
+func DecryptRequest(content io.Reader, sseKey SSECustomerKey, offset int64, metadata map[string]string) (io.Reader, error) {
+	baseNonce, err := base64.StdEncoding.DecodeString(metadata[metaSSEIV])
+	if err != nil {
+		return nil, errObjectNotEncrypted
+	}
+	sealedAndNonce, err := base64.StdEncoding.DecodeString(metadata[metaSSESealedKey])
+	if err != nil {
+		return nil, errObjectNotEncrypted
+	}
+
+	block, err := aes.NewCipher(sseKey.Key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := gcm.NonceSize()
+	if len(sealedAndNonce) < nonceSize {
+		return nil, errObjectNotEncrypted
+	}
+	keyNonce, sealed := sealedAndNonce[:nonceSize], sealedAndNonce[nonceSize:]
+
+	dek, err := unsealDEK(sseKey.Key, sealed, keyNonce)
+	if err != nil {
+		return nil, err
+	}
+
+	dataBlock, err := aes.NewCipher(dek)
+	if err != nil {
+		return nil, err
+	}
+	dataGCM, err := cipher.NewGCM(dataBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkIndex := uint64(offset / sseChunkSize)
+	skip := offset % sseChunkSize
+
+	reader := &sseDecryptReader{src: content, gcm: dataGCM, baseNonce: baseNonce, chunkIndex: chunkIndex}
+	if skip > 0 {
+		if _, err := io.CopyN(ioutil.Discard, reader, skip); err != nil {
+			return nil, err
+		}
+	}
+	return reader, nil
+}
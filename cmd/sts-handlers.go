@@ -0,0 +1,674 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	ldap "gopkg.in/ldap.v3"
+)
+
+const (
+	// Header and presigned query parameter carrying the session token
+	// of a temporary credential minted through the STS endpoints.
+	amzSecurityToken      = "X-Amz-Security-Token"
+	amzSecurityTokenQuery = "x-amz-security-token"
+
+	// Default validity duration handed out by AssumeRole when the
+	// caller does not ask for a specific DurationSeconds.
+	stsDefaultDuration = 1 * time.Hour
+	stsMinDuration     = 15 * time.Minute
+	stsMaxDuration     = 7 * 24 * time.Hour
+)
+
+// stsErrorCode - STS specific error codes, rendered in the same XML
+// error document shape AWS STS uses.
+type stsErrorCode string
+
+const (
+	stsErrInvalidParameterValue stsErrorCode = "InvalidParameterValue"
+	stsErrMissingParameter      stsErrorCode = "MissingParameter"
+	stsErrInternalError         stsErrorCode = "InternalError"
+	stsErrAccessDenied          stsErrorCode = "AccessDenied"
+)
+
+// stsErrorResponse - XML error document returned by the STS endpoints,
+// matching the shape of AWS STS error responses.
+type stsErrorResponse struct {
+	XMLName xml.Name `xml:"https://sts.amazonaws.com/doc/2011-06-15/ ErrorResponse"`
+	Error   struct {
+		Type    string `xml:"Type"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Error"`
+	RequestID string `xml:"RequestId"`
+}
+
+func writeSTSErrorResponse(w http.ResponseWriter, code stsErrorCode, msg string) {
+	resp := stsErrorResponse{}
+	resp.Error.Type = "Sender"
+	resp.Error.Code = string(code)
+	resp.Error.Message = msg
+	resp.RequestID = mustGetRequestID(time.Now())
+	writeResponse(w, http.StatusBadRequest, encodeXMLResponse(resp), mimeXML)
+}
+
+// assumedRoleUser - identifies the temporary identity handed back by
+// the STS endpoints, mirroring the AWS STS `AssumedRoleUser` shape.
+type assumedRoleUser struct {
+	Arn           string `xml:"Arn"`
+	AssumedRoleID string `xml:"AssumedRoleId"`
+}
+
+// stsCredentials - the AccessKeyId/SecretAccessKey/SessionToken/Expiration
+// quad returned by every AssumeRole* call.
+type stsCredentials struct {
+	AccessKeyID     string    `xml:"AccessKeyId"`
+	SecretAccessKey string    `xml:"SecretAccessKey"`
+	SessionToken    string    `xml:"SessionToken"`
+	Expiration      time.Time `xml:"Expiration"`
+}
+
+type assumeRoleResult struct {
+	Credentials      stsCredentials  `xml:"Credentials"`
+	AssumedRoleUser  assumedRoleUser `xml:"AssumedRoleUser"`
+	PackedPolicySize int             `xml:"PackedPolicySize"`
+}
+
+type assumeRoleResponse struct {
+	XMLName xml.Name          `xml:"https://sts.amazonaws.com/doc/2011-06-15/ AssumeRoleResponse"`
+	Result  assumeRoleResult  `xml:"AssumeRoleResult"`
+	Meta    stsResponseMetadata `xml:"ResponseMetadata"`
+}
+
+type stsResponseMetadata struct {
+	RequestID string `xml:"RequestId"`
+}
+
+// stsTempCredsTable - the in-memory table of temporary credentials
+// minted by the STS handlers below. An expired entry is rejected by
+// resolveRequestCredential on lookup, and removed periodically by
+// purgeExpired (see startSTSCredentialPurging).
+type stsTempCredsTable struct {
+	mu    sync.RWMutex
+	creds map[string]credential
+}
+
+var globalSTSTempCreds = &stsTempCredsTable{creds: make(map[string]credential)}
+
+func (t *stsTempCredsTable) put(cred credential) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.creds[cred.AccessKey] = cred
+}
+
+func (t *stsTempCredsTable) get(accessKey string) (credential, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	cred, ok := t.creds[accessKey]
+	return cred, ok
+}
+
+// purgeExpired - drops every credential whose Expiry has already
+// passed. Called periodically from a background goroutine so the
+// table does not grow without bound on a long running server.
+func (t *stsTempCredsTable) purgeExpired() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for accessKey, cred := range t.creds {
+		if cred.IsExpired() {
+			delete(t.creds, accessKey)
+		}
+	}
+}
+
+// startSTSCredentialPurging - launches the background goroutine that
+// periodically removes expired temporary credentials from the
+// in-memory table. Meant to be called once at server startup.
+func startSTSCredentialPurging() {
+	go func() {
+		ticker := time.NewTicker(5 * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			globalSTSTempCreds.purgeExpired()
+		}
+	}()
+}
+
+// stsDuration - parses and clamps the DurationSeconds form value to
+// the [stsMinDuration, stsMaxDuration] range, defaulting to
+// stsDefaultDuration when absent or invalid.
+func stsDuration(r *http.Request) time.Duration {
+	durationSeconds := r.FormValue("DurationSeconds")
+	if durationSeconds == "" {
+		return stsDefaultDuration
+	}
+	secs, err := parseDurationSeconds(durationSeconds)
+	if err != nil {
+		return stsDefaultDuration
+	}
+	d := time.Duration(secs) * time.Second
+	switch {
+	case d < stsMinDuration:
+		return stsMinDuration
+	case d > stsMaxDuration:
+		return stsMaxDuration
+	default:
+		return d
+	}
+}
+
+// mintSTSCredential - generates a new temporary credential valid for
+// the given duration, registers it in the in-memory table and
+// returns it ready to be serialized into an STS response.
+func mintSTSCredential(duration time.Duration) (credential, error) {
+	expiry := time.Now().UTC().Add(duration)
+	cred, err := getNewCredentialWithExpiry(expiry)
+	if err != nil {
+		return credential{}, err
+	}
+	globalSTSTempCreds.put(cred)
+	return cred, nil
+}
+
+func writeAssumeRoleResponse(w http.ResponseWriter, roleArn, roleSessionName string, cred credential) {
+	resp := assumeRoleResponse{
+		Result: assumeRoleResult{
+			Credentials: stsCredentials{
+				AccessKeyID:     cred.AccessKey,
+				SecretAccessKey: cred.SecretKey,
+				SessionToken:    cred.SessionToken,
+				Expiration:      cred.Expiry,
+			},
+			AssumedRoleUser: assumedRoleUser{
+				Arn:           roleArn,
+				AssumedRoleID: roleSessionName + ":" + cred.AccessKey,
+			},
+		},
+		Meta: stsResponseMetadata{RequestID: mustGetRequestID(time.Now())},
+	}
+	writeResponse(w, http.StatusOK, encodeXMLResponse(resp), mimeXML)
+}
+
+var (
+	errOpenIDNotConfigured      = errors.New("sts: no OpenID provider is configured for AssumeRoleWithClientGrants")
+	errInvalidClientGrantsToken = errors.New("sts: client grants token failed verification")
+	errNoMatchingJWKSKey        = errors.New("sts: no JWKS key matches the token's kid")
+	errLDAPNotConfigured        = errors.New("sts: no LDAP server is configured for AssumeRoleWithLDAPIdentity")
+)
+
+// clientGrantsClaims - the subset of standard JWT claims this server
+// relies on when validating an AssumeRoleWithClientGrants token; the
+// verified Subject becomes the assumed role session's identity.
+type clientGrantsClaims struct {
+	jwtgo.StandardClaims
+}
+
+// jwksKey - a single JSON Web Key as published by an OpenID provider's
+// JWKS endpoint, restricted to the RSA fields this server needs to
+// verify a client grants token's signature.
+type jwksKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwksDocument struct {
+	Keys []jwksKey `json:"keys"`
+}
+
+// fetchJWKSPublicKey - downloads the configured OpenID provider's JWKS
+// document and returns the RSA public key matching kid. Re-fetched on
+// every call rather than cached: AssumeRoleWithClientGrants is rare
+// enough that the extra round trip is cheaper than serving a stale
+// key past the provider's own rotation.
+func fetchJWKSPublicKey(jwksURL, kid string) (*rsa.PublicKey, error) {
+	resp, err := http.Get(jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var doc jwksDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, err
+	}
+	for _, key := range doc.Keys {
+		if key.Kid != kid || key.Kty != "RSA" {
+			continue
+		}
+		nBytes, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, err
+		}
+		eBytes, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(nBytes),
+			E: int(new(big.Int).SetBytes(eBytes).Int64()),
+		}, nil
+	}
+	return nil, errNoMatchingJWKSKey
+}
+
+// verifyClientGrantsToken - validates token as a JWT signed by the
+// configured OpenID provider's published key and returns its Subject
+// claim. A server with no OpenID provider configured rejects every
+// token outright, rather than minting a credential nothing vouches for.
+func verifyClientGrantsToken(token string) (string, error) {
+	jwksURL := globalServerConfig.OpenID.JWKSURL
+	if jwksURL == "" {
+		return "", errOpenIDNotConfigured
+	}
+
+	claims := &clientGrantsClaims{}
+	parsed, err := jwtgo.ParseWithClaims(token, claims, func(t *jwtgo.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return fetchJWKSPublicKey(jwksURL, kid)
+	})
+	if err != nil || !parsed.Valid || claims.Subject == "" {
+		return "", errInvalidClientGrantsToken
+	}
+	return claims.Subject, nil
+}
+
+// verifyLDAPIdentity - binds to the configured LDAP server as
+// ldapUsername with ldapPassword, returning an error unless the bind
+// succeeds. This is the actual proof of identity
+// AssumeRoleWithLDAPIdentity requires before a credential is minted.
+func verifyLDAPIdentity(ldapUsername, ldapPassword string) error {
+	addr := globalServerConfig.LDAPServer.Addr
+	if addr == "" {
+		return errLDAPNotConfigured
+	}
+	conn, err := ldap.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	bindDN := fmt.Sprintf(globalServerConfig.LDAPServer.UsernameFormat, ldapUsername)
+	return conn.Bind(bindDN, ldapPassword)
+}
+
+// AssumeRole - POST /?Action=AssumeRole
+// Issues a short-lived credential to a caller that already holds a
+// valid long-term or temporary credential, scoped to the requested
+// duration. Unlike the other two AssumeRole* actions, the proof of
+// identity here is the caller's existing v4 request signature - the
+// same check every other signed request on this server goes through.
+func (api objectAPIHandlers) AssumeRole(w http.ResponseWriter, r *http.Request) {
+	if apiErr := isReqAuthenticatedSTS(r); apiErr != ErrNone {
+		writeSTSErrorResponse(w, stsErrAccessDenied, "Access Denied")
+		return
+	}
+
+	roleArn := r.FormValue("RoleArn")
+	roleSessionName := r.FormValue("RoleSessionName")
+	if roleSessionName == "" {
+		writeSTSErrorResponse(w, stsErrMissingParameter, "RoleSessionName is required")
+		return
+	}
+
+	cred, err := mintSTSCredential(stsDuration(r))
+	if err != nil {
+		writeSTSErrorResponse(w, stsErrInternalError, err.Error())
+		return
+	}
+
+	writeAssumeRoleResponse(w, roleArn, roleSessionName, cred)
+}
+
+// AssumeRoleWithClientGrants - POST /?Action=AssumeRoleWithClientGrants
+// Exchanges a third party OAuth2/OpenID Connect token for a temporary
+// credential. The token's signature is verified against the
+// configured OpenID provider's published JWKS key before any
+// credential is minted; its Subject claim becomes the assumed role
+// session's identity.
+func (api objectAPIHandlers) AssumeRoleWithClientGrants(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("Token")
+	if token == "" {
+		writeSTSErrorResponse(w, stsErrMissingParameter, "Token is required")
+		return
+	}
+
+	subject, err := verifyClientGrantsToken(token)
+	if err != nil {
+		writeSTSErrorResponse(w, stsErrAccessDenied, "Token is invalid: "+err.Error())
+		return
+	}
+	roleSessionName := r.FormValue("RoleSessionName")
+	if roleSessionName == "" {
+		roleSessionName = subject
+	}
+
+	cred, err := mintSTSCredential(stsDuration(r))
+	if err != nil {
+		writeSTSErrorResponse(w, stsErrInternalError, err.Error())
+		return
+	}
+
+	writeAssumeRoleResponse(w, r.FormValue("RoleArn"), roleSessionName, cred)
+}
+
+// AssumeRoleWithLDAPIdentity - POST /?Action=AssumeRoleWithLDAPIdentity
+// Exchanges LDAP bind credentials for a temporary credential. The
+// LDAP bind is performed against the configured LDAP server before
+// any credential is minted - a failed bind never reaches
+// mintSTSCredential.
+func (api objectAPIHandlers) AssumeRoleWithLDAPIdentity(w http.ResponseWriter, r *http.Request) {
+	ldapUsername := r.FormValue("LDAPUsername")
+	ldapPassword := r.FormValue("LDAPPassword")
+	if ldapUsername == "" || ldapPassword == "" {
+		writeSTSErrorResponse(w, stsErrMissingParameter, "LDAPUsername and LDAPPassword are required")
+		return
+	}
+
+	if err := verifyLDAPIdentity(ldapUsername, ldapPassword); err != nil {
+		writeSTSErrorResponse(w, stsErrAccessDenied, "LDAP authentication failed")
+		return
+	}
+
+	cred, err := mintSTSCredential(stsDuration(r))
+	if err != nil {
+		writeSTSErrorResponse(w, stsErrInternalError, err.Error())
+		return
+	}
+
+	writeAssumeRoleResponse(w, r.FormValue("RoleArn"), ldapUsername, cred)
+}
+
+// registerSTSRouter - registers the STS Action=* endpoints on the
+// root path of the given router.
+func registerSTSRouter(router *mux.Router, api objectAPIHandlers) {
+	stsRouter := router.NewRoute().PathPrefix("/").Subrouter()
+	stsRouter.Methods("POST").HeadersRegexp("Content-Type", "application/x-www-form-urlencoded*").
+		Queries("Action", "AssumeRole").HandlerFunc(api.AssumeRole)
+	stsRouter.Methods("POST").HeadersRegexp("Content-Type", "application/x-www-form-urlencoded*").
+		Queries("Action", "AssumeRoleWithClientGrants").HandlerFunc(api.AssumeRoleWithClientGrants)
+	stsRouter.Methods("POST").HeadersRegexp("Content-Type", "application/x-www-form-urlencoded*").
+		Queries("Action", "AssumeRoleWithLDAPIdentity").HandlerFunc(api.AssumeRoleWithLDAPIdentity)
+}
+
+// securityTokenFromRequest - extracts the session token presented by
+// the client, either as the X-Amz-Security-Token header (used by
+// standard v4 signed requests) or the x-amz-security-token query
+// parameter (used by presigned URLs).
+func securityTokenFromRequest(r *http.Request) string {
+	if token := r.Header.Get(amzSecurityToken); token != "" {
+		return token
+	}
+	return r.URL.Query().Get(amzSecurityTokenQuery)
+}
+
+// checkCredentialNotExpired - rejects the request if the credential
+// resolved during v4 signature verification is a temporary
+// credential whose Expiry has already passed.
+func checkCredentialNotExpired(cred credential) APIErrorCode {
+	if cred.IsExpired() {
+		return ErrExpiredPresignRequest
+	}
+	return ErrNone
+}
+
+// credentialHeaderValue - extracts the raw "accessKey/date/region/
+// service/aws4_request" credential string a request is signed with,
+// from either the "Authorization: AWS4-HMAC-SHA256 Credential=...,
+// ..." header or, for presigned URLs, the x-amz-credential query
+// parameter.
+func credentialHeaderValue(r *http.Request) (string, error) {
+	credentialStr := r.URL.Query().Get("X-Amz-Credential")
+	if credentialStr == "" {
+		auth := r.Header.Get("Authorization")
+		if idx := strings.Index(auth, "Credential="); idx != -1 {
+			rest := auth[idx+len("Credential="):]
+			if end := strings.IndexByte(rest, ','); end != -1 {
+				rest = rest[:end]
+			}
+			credentialStr = strings.TrimSpace(rest)
+		}
+	}
+	if credentialStr == "" {
+		return "", errInvalidAccessKeyLength
+	}
+	return credentialStr, nil
+}
+
+// requestAccessKey - extracts the access key a request is signed
+// with, from the credential string credentialHeaderValue resolves.
+func requestAccessKey(r *http.Request) (string, error) {
+	credentialStr, err := credentialHeaderValue(r)
+	if err != nil {
+		return "", err
+	}
+	accessKey, _, err := parseCredentialHeader(credentialStr)
+	return accessKey, err
+}
+
+// resolveRequestCredential - resolves the credential a request is
+// signed with, recognizing both the server's root credential and any
+// still-valid temporary credential minted by the STS endpoints above.
+// A resolved temporary credential that has expired, or whose
+// X-Amz-Security-Token does not match, is rejected here rather than
+// left for the handler to discover the hard way.
+func resolveRequestCredential(r *http.Request) (credential, APIErrorCode) {
+	accessKey, err := requestAccessKey(r)
+	if err != nil {
+		return credential{}, ErrInvalidAccessKeyID
+	}
+
+	rootCred := globalServerConfig.GetCredential()
+	if accessKey == rootCred.AccessKey {
+		return rootCred, ErrNone
+	}
+
+	cred, ok := globalSTSTempCreds.get(accessKey)
+	if !ok {
+		return credential{}, ErrInvalidAccessKeyID
+	}
+	if code := checkCredentialNotExpired(cred); code != ErrNone {
+		return credential{}, code
+	}
+	if !isValidSessionToken(cred, securityTokenFromRequest(r), rootCred.SecretKey) {
+		return credential{}, ErrSignatureDoesNotMatch
+	}
+	return cred, ErrNone
+}
+
+// requestTimestamp - parses the X-Amz-Date a v4 signed request
+// carries, from the header (a standard signed request) or the query
+// parameter (a presigned URL).
+func requestTimestamp(r *http.Request) (time.Time, error) {
+	amzDate := r.Header.Get("X-Amz-Date")
+	if amzDate == "" {
+		amzDate = r.URL.Query().Get("X-Amz-Date")
+	}
+	return time.Parse(iso8601Format, amzDate)
+}
+
+// signedHeaderNames - the SignedHeaders names a client included in its
+// Authorization header, or the X-Amz-SignedHeaders query parameter
+// for a presigned URL.
+func signedHeaderNames(r *http.Request) []string {
+	signedHeaders := r.URL.Query().Get("X-Amz-SignedHeaders")
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if idx := strings.Index(auth, "SignedHeaders="); idx != -1 {
+			rest := auth[idx+len("SignedHeaders="):]
+			if end := strings.IndexByte(rest, ','); end != -1 {
+				rest = rest[:end]
+			}
+			signedHeaders = strings.TrimSpace(rest)
+		}
+	}
+	if signedHeaders == "" {
+		return nil
+	}
+	return strings.Split(signedHeaders, ";")
+}
+
+// requestSignature - the Signature a client computed, from either the
+// Authorization header or the X-Amz-Signature query parameter.
+func requestSignature(r *http.Request) string {
+	if sig := r.URL.Query().Get("X-Amz-Signature"); sig != "" {
+		return sig
+	}
+	auth := r.Header.Get("Authorization")
+	idx := strings.Index(auth, "Signature=")
+	if idx == -1 {
+		return ""
+	}
+	return auth[idx+len("Signature="):]
+}
+
+// canonicalRequestString - builds the v4 canonical request for r over
+// the given signed header names and pre-computed payload hash, per
+// the AWS Signature Version 4 spec.
+func canonicalRequestString(r *http.Request, headers []string, hashedPayload string) string {
+	query := r.URL.Query()
+	query.Del("X-Amz-Signature")
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var canonicalQuery []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			canonicalQuery = append(canonicalQuery, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+
+	sortedHeaders := append([]string(nil), headers...)
+	sort.Strings(sortedHeaders)
+	var canonicalHeaders strings.Builder
+	for _, h := range sortedHeaders {
+		canonicalHeaders.WriteString(h)
+		canonicalHeaders.WriteByte(':')
+		if h == "host" {
+			canonicalHeaders.WriteString(r.Host)
+		} else {
+			canonicalHeaders.WriteString(strings.Join(r.Header[http.CanonicalHeaderKey(h)], ","))
+		}
+		canonicalHeaders.WriteByte('\n')
+	}
+
+	return strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		strings.Join(canonicalQuery, "&"),
+		canonicalHeaders.String(),
+		strings.Join(sortedHeaders, ";"),
+		hashedPayload,
+	}, "\n")
+}
+
+// sha256Hex - lowercase hex-encoded SHA-256 digest, used both for the
+// payload hash and for hashing the canonical request below.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyV4RequestSignature - verifies that r was signed with cred's
+// own secret key. cred is expected to already be resolved through
+// resolveRequestCredential, so it may be the root credential or a
+// still-valid STS temporary credential - unlike the stock
+// isReqAuthenticated, which only ever checks against the root
+// credential.
+func verifyV4RequestSignature(r *http.Request, cred credential) APIErrorCode {
+	credentialStr, err := credentialHeaderValue(r)
+	if err != nil {
+		return ErrInvalidAccessKeyID
+	}
+	_, scope, err := parseCredentialHeader(credentialStr)
+	if err != nil {
+		return ErrInvalidAccessKeyID
+	}
+	date, err := requestTimestamp(r)
+	if err != nil {
+		return ErrMalformedDate
+	}
+	headers := signedHeaderNames(r)
+	if len(headers) == 0 {
+		return ErrMissingSecurityHeader
+	}
+
+	hashedPayload := r.Header.Get("X-Amz-Content-Sha256")
+	if hashedPayload == "" {
+		hashedPayload = "UNSIGNED-PAYLOAD"
+	}
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		date.Format(iso8601Format),
+		scope,
+		sha256Hex([]byte(canonicalRequestString(r, headers, hashedPayload))),
+	}, "\n")
+
+	signingKey := getSigningKey(cred.SecretKey, date, credentialStr)
+	expectedSignature := getSignature(signingKey, stringToSign)
+
+	if !compareSignatureV4(expectedSignature, requestSignature(r)) {
+		return ErrSignatureDoesNotMatch
+	}
+	return ErrNone
+}
+
+// isReqAuthenticatedSTS - the v4 signature check every handler in this
+// series runs in place of the stock isReqAuthenticated: it resolves
+// the request's credential through resolveRequestCredential first, so
+// a still-valid STS temporary credential is recognized and its
+// expiry/session-token checked, and only then verifies the signature
+// against that credential's own secret key. Without this, a credential
+// minted by AssumeRole could never successfully sign a real
+// PutObject/GetObject/DeleteObject/CopyObject/ComposeObject request -
+// only PostPolicyHandler's separate credential-resolution path would
+// ever accept it.
+func isReqAuthenticatedSTS(r *http.Request) APIErrorCode {
+	cred, apiErr := resolveRequestCredential(r)
+	if apiErr != ErrNone {
+		return apiErr
+	}
+	return verifyV4RequestSignature(r, cred)
+}
+
+// startSTSSubsystem - mounts the STS Action=* endpoints on router and
+// starts the background expired-credential purge goroutine. Meant to
+// be called once, alongside the rest of the API router setup, at
+// server startup.
+func startSTSSubsystem(router *mux.Router, api objectAPIHandlers) {
+	registerSTSRouter(router, api)
+	startSTSCredentialPurging()
+}
@@ -0,0 +1,468 @@
+/*
+ * Minio Cloud Storage, (C) 2017, 2018 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"hash/crc32"
+	"io"
+	"sort"
+
+	"github.com/minio/minio/pkg/policy"
+)
+
+var (
+	errXLMinDisks = errors.New("Number of disks is lesser than the minimum erasure set size of 4")
+	errXLMaxDisks = errors.New("Total number of disks does not decompose into any supported erasure set size")
+	errXLNumDisks = errors.New("Total number of disks is not a whole multiple of a supported erasure set size")
+)
+
+// validSetSizes - the erasure set widths this server knows how to
+// drive, largest first. possibleSetSizes walks this list in
+// order so that, among every setSize which evenly divides the total
+// disk count, the widest (and therefore fewest-sets) option wins.
+var validSetSizes = []int{16, 14, 12, 10, 8, 6, 4}
+
+// xlSetsConfig - the erasure set topology computed for a given
+// collection of storage endpoints: setCount sets of setSize disks
+// each, in the order the endpoints were supplied.
+type xlSetsConfig struct {
+	setCount int
+	setSize  int
+}
+
+// possibleSetSizes - returns setCount and setSize for diskCount,
+// choosing the largest setSize in validSetSizes that evenly divides
+// diskCount. A larger setSize both maximizes per-set erasure width
+// and minimizes the resulting setCount, so a single linear scan over
+// validSetSizes (descending) satisfies both goals at once.
+func possibleSetSizes(diskCount int) (xlSetsConfig, error) {
+	if diskCount < validSetSizes[len(validSetSizes)-1] {
+		return xlSetsConfig{}, errXLMinDisks
+	}
+	for _, setSize := range validSetSizes {
+		if diskCount%setSize == 0 {
+			return xlSetsConfig{setCount: diskCount / setSize, setSize: setSize}, nil
+		}
+	}
+	return xlSetsConfig{}, errXLNumDisks
+}
+
+// checkSufficientDisks - validates that len(endpoints) decomposes
+// into N erasure sets of a supported width (setSize in
+// {4,6,8,10,12,14,16}, N >= 1). Unlike the single-set-only
+// implementation this replaces, any disk count above 16 is no longer
+// automatically rejected - it is instead partitioned into multiple
+// erasure sets.
+func checkSufficientDisks(endpoints EndpointList) error {
+	_, err := possibleSetSizes(len(endpoints))
+	if err == errXLNumDisks && len(endpoints) > validSetSizes[0] {
+		// Keep the historical error for the common case of "too
+		// many disks, none of the supported widths divide them" -
+		// distinguishing it from "too few disks" is more useful to
+		// an operator reading the startup log than a single generic
+		// error would be.
+		return errXLMaxDisks
+	}
+	return err
+}
+
+// partitionEndpointsIntoSets - splits endpoints into setCount
+// contiguous erasure sets of setSize, in the order they were given
+// on the command line. Order matters: objectSetIndex below assumes
+// set i always refers to the same setSize-wide slice of endpoints
+// across restarts.
+func partitionEndpointsIntoSets(endpoints EndpointList, cfg xlSetsConfig) [][]Endpoint {
+	sets := make([][]Endpoint, cfg.setCount)
+	for i := 0; i < cfg.setCount; i++ {
+		sets[i] = endpoints[i*cfg.setSize : (i+1)*cfg.setSize]
+	}
+	return sets
+}
+
+// objectSetIndex - deterministically maps object to one of setCount
+// erasure sets using CRC32 of its path, so that every request for the
+// same object - regardless of which server in the cluster receives
+// it - is always routed to the same set.
+func objectSetIndex(object string, setCount int) int {
+	return int(crc32.ChecksumIEEE([]byte(object)) % uint32(setCount))
+}
+
+// xlSets - fans object operations out to the owning erasure set,
+// computed deterministically from the object path. Bucket operations
+// are broadcast to every set since buckets are not partitioned.
+type xlSets struct {
+	sets []*xlObjects
+}
+
+// newXLObjects - partitions endpoints into erasure sets per
+// checkSufficientDisks/possibleSetSizes and constructs one xlObjects
+// backend per set.
+func newXLObjects(endpoints EndpointList) (ObjectLayer, error) {
+	if err := checkSufficientDisks(endpoints); err != nil {
+		return nil, err
+	}
+	cfg, err := possibleSetSizes(len(endpoints))
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := partitionEndpointsIntoSets(endpoints, cfg)
+	sets := make([]*xlObjects, len(partitions))
+	for i, setEndpoints := range partitions {
+		set, err := newXLObjectsSet(setEndpoints)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+
+	if len(sets) == 1 {
+		// A single erasure set behaves exactly like the
+		// pre-existing single-set xlObjects - no need to pay the
+		// extra indirection of routing through xlSets.
+		return sets[0], nil
+	}
+	return &xlSets{sets: sets}, nil
+}
+
+// setForObject - returns the erasure set object should be read from
+// or written to.
+func (s *xlSets) setForObject(object string) *xlObjects {
+	return s.sets[objectSetIndex(object, len(s.sets))]
+}
+
+// MakeBucket - buckets are not partitioned by set, so every set must
+// carry the same bucket; created on all of them so an object landing
+// in any set finds its bucket already there.
+func (s *xlSets) MakeBucket(bucket string) error {
+	for _, set := range s.sets {
+		if err := set.MakeBucket(bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetBucketInfo - buckets are mirrored across every set, so the first
+// set's view of the bucket is authoritative.
+func (s *xlSets) GetBucketInfo(bucket string) (BucketInfo, error) {
+	return s.sets[0].GetBucketInfo(bucket)
+}
+
+// ListBuckets - buckets are mirrored across every set, so listing the
+// first set's buckets is sufficient.
+func (s *xlSets) ListBuckets() ([]BucketInfo, error) {
+	return s.sets[0].ListBuckets()
+}
+
+// DeleteBucket - removes bucket from every set, since MakeBucket
+// created it on every set.
+func (s *xlSets) DeleteBucket(bucket string) error {
+	for _, set := range s.sets {
+		if err := set.DeleteBucket(bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetObject - reads from the erasure set object hashes to.
+func (s *xlSets) GetObject(bucket, object string, startOffset, length int64, writer io.Writer) error {
+	return s.setForObject(object).GetObject(bucket, object, startOffset, length, writer)
+}
+
+// GetObjectInfo - see GetObject.
+func (s *xlSets) GetObjectInfo(bucket, object string) (ObjectInfo, error) {
+	return s.setForObject(object).GetObjectInfo(bucket, object)
+}
+
+// PutObject - writes to the erasure set object hashes to.
+func (s *xlSets) PutObject(bucket, object string, size int64, data io.Reader, metadata map[string]string) (ObjectInfo, error) {
+	return s.setForObject(object).PutObject(bucket, object, size, data, metadata)
+}
+
+// DeleteObject - see GetObject.
+func (s *xlSets) DeleteObject(bucket, object string) error {
+	return s.setForObject(object).DeleteObject(bucket, object)
+}
+
+// CopyObject - source and destination object may hash to different
+// sets, so a same-set copy is delegated directly while a cross-set
+// copy is satisfied by reading the source set and writing the
+// destination set.
+func (s *xlSets) CopyObject(srcBucket, srcObject, dstBucket, dstObject string, srcInfo ObjectInfo) (ObjectInfo, error) {
+	srcSet := s.setForObject(srcObject)
+	dstSet := s.setForObject(dstObject)
+	if srcSet == dstSet {
+		return srcSet.CopyObject(srcBucket, srcObject, dstBucket, dstObject, srcInfo)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(srcSet.GetObject(srcBucket, srcObject, 0, srcInfo.Size, pw))
+	}()
+	defer pr.Close()
+	return dstSet.PutObject(dstBucket, dstObject, srcInfo.Size, pr, srcInfo.UserDefined)
+}
+
+// ComposeObject - shares the same driver as fsObjects/xlObjects; the
+// per-source CopyObject/CopyObjectPart calls it makes are routed
+// through xlSets's own implementations above, so composition works
+// the same whether or not a source crosses a set boundary.
+func (s *xlSets) ComposeObject(dstBucket, dstObject string, srcs []ComposeSource, sseOpts composeSSEOptions) (ObjectInfo, error) {
+	return composeObjectCommon(s, dstBucket, dstObject, srcs, sseOpts)
+}
+
+// NewMultipartUpload - the upload is keyed by the destination object,
+// so it is driven entirely by the set that object hashes to.
+func (s *xlSets) NewMultipartUpload(bucket, object string, metadata map[string]string) (string, error) {
+	return s.setForObject(object).NewMultipartUpload(bucket, object, metadata)
+}
+
+// CopyObjectPart - the source may live in a different set than the
+// destination object's upload; mirrors CopyObject's cross-set path.
+func (s *xlSets) CopyObjectPart(srcBucket, srcObject, dstBucket, dstObject, uploadID string, partNumber int, startOffset, length int64) (PartInfo, error) {
+	srcSet := s.setForObject(srcObject)
+	dstSet := s.setForObject(dstObject)
+	if srcSet == dstSet {
+		return srcSet.CopyObjectPart(srcBucket, srcObject, dstBucket, dstObject, uploadID, partNumber, startOffset, length)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(srcSet.GetObject(srcBucket, srcObject, startOffset, length, pw))
+	}()
+	defer pr.Close()
+	return dstSet.PutObjectPart(dstBucket, dstObject, uploadID, partNumber, length, pr)
+}
+
+// PutObjectPart - see NewMultipartUpload.
+func (s *xlSets) PutObjectPart(bucket, object, uploadID string, partNumber int, size int64, data io.Reader) (PartInfo, error) {
+	return s.setForObject(object).PutObjectPart(bucket, object, uploadID, partNumber, size, data)
+}
+
+// AbortMultipartUpload - see NewMultipartUpload.
+func (s *xlSets) AbortMultipartUpload(bucket, object, uploadID string) error {
+	return s.setForObject(object).AbortMultipartUpload(bucket, object, uploadID)
+}
+
+// CompleteMultipartUpload - see NewMultipartUpload.
+func (s *xlSets) CompleteMultipartUpload(bucket, object, uploadID string, parts []CompletePart) (ObjectInfo, error) {
+	return s.setForObject(object).CompleteMultipartUpload(bucket, object, uploadID, parts)
+}
+
+// ListObjects - objects are scattered across every set by hash, so
+// every set must be queried and the results merged; matches the
+// single-set xlObjects ordering by sorting the merged result.
+func (s *xlSets) ListObjects(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
+	var merged ListObjectsInfo
+	for _, set := range s.sets {
+		result, err := set.ListObjects(bucket, prefix, marker, delimiter, maxKeys)
+		if err != nil {
+			return ListObjectsInfo{}, err
+		}
+		merged.Objects = append(merged.Objects, result.Objects...)
+		merged.Prefixes = append(merged.Prefixes, result.Prefixes...)
+		if result.IsTruncated {
+			merged.IsTruncated = true
+		}
+	}
+	sort.Slice(merged.Objects, func(i, j int) bool {
+		return merged.Objects[i].Name < merged.Objects[j].Name
+	})
+	if len(merged.Objects) > maxKeys {
+		merged.Objects = merged.Objects[:maxKeys]
+		merged.IsTruncated = true
+	}
+	return merged, nil
+}
+
+// ListMultipartUploads - in-progress uploads are keyed by the
+// destination object, so like ListObjects every set must be queried
+// and the results merged.
+func (s *xlSets) ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarker, delimiter string, maxUploads int) (ListMultipartsInfo, error) {
+	var merged ListMultipartsInfo
+	for _, set := range s.sets {
+		result, err := set.ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarker, delimiter, maxUploads)
+		if err != nil {
+			return ListMultipartsInfo{}, err
+		}
+		merged.Uploads = append(merged.Uploads, result.Uploads...)
+		if result.IsTruncated {
+			merged.IsTruncated = true
+		}
+	}
+	if len(merged.Uploads) > maxUploads {
+		merged.Uploads = merged.Uploads[:maxUploads]
+		merged.IsTruncated = true
+	}
+	return merged, nil
+}
+
+// ListObjectParts - an in-progress upload is driven entirely by the
+// set its destination object hashes to; see NewMultipartUpload.
+func (s *xlSets) ListObjectParts(bucket, object, uploadID string, partNumberMarker, maxParts int) (ListPartsInfo, error) {
+	return s.setForObject(object).ListObjectParts(bucket, object, uploadID, partNumberMarker, maxParts)
+}
+
+// Shutdown - releases every set's resources in turn, returning the
+// first error encountered but still shutting down every remaining set
+// rather than abandoning them at the first failure.
+func (s *xlSets) Shutdown() error {
+	var firstErr error
+	for _, set := range s.sets {
+		if err := set.Shutdown(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// StorageInfo - aggregates every set's disk usage/capacity into a
+// single cluster-wide total.
+func (s *xlSets) StorageInfo() StorageInfo {
+	var total StorageInfo
+	for _, set := range s.sets {
+		info := set.StorageInfo()
+		total.Total += info.Total
+		total.Free += info.Free
+	}
+	return total
+}
+
+// HealBucket - a bucket is mirrored across every set, so healing it
+// means healing it on every set.
+func (s *xlSets) HealBucket(bucket string) error {
+	for _, set := range s.sets {
+		if err := set.HealBucket(bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HealObject - see GetObject: driven entirely by the set object
+// hashes to.
+func (s *xlSets) HealObject(bucket, object string) error {
+	return s.setForObject(object).HealObject(bucket, object)
+}
+
+// ListObjectsHeal - objects needing heal are scattered across every
+// set by hash, so every set must be queried and the results merged;
+// mirrors ListObjects.
+func (s *xlSets) ListObjectsHeal(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
+	var merged ListObjectsInfo
+	for _, set := range s.sets {
+		result, err := set.ListObjectsHeal(bucket, prefix, marker, delimiter, maxKeys)
+		if err != nil {
+			return ListObjectsInfo{}, err
+		}
+		merged.Objects = append(merged.Objects, result.Objects...)
+		if result.IsTruncated {
+			merged.IsTruncated = true
+		}
+	}
+	if len(merged.Objects) > maxKeys {
+		merged.Objects = merged.Objects[:maxKeys]
+		merged.IsTruncated = true
+	}
+	return merged, nil
+}
+
+// ListUploadsHeal - mirrors ListMultipartUploads.
+func (s *xlSets) ListUploadsHeal(bucket, prefix, keyMarker, uploadIDMarker, delimiter string, maxUploads int) (ListMultipartsInfo, error) {
+	var merged ListMultipartsInfo
+	for _, set := range s.sets {
+		result, err := set.ListUploadsHeal(bucket, prefix, keyMarker, uploadIDMarker, delimiter, maxUploads)
+		if err != nil {
+			return ListMultipartsInfo{}, err
+		}
+		merged.Uploads = append(merged.Uploads, result.Uploads...)
+		if result.IsTruncated {
+			merged.IsTruncated = true
+		}
+	}
+	if len(merged.Uploads) > maxUploads {
+		merged.Uploads = merged.Uploads[:maxUploads]
+		merged.IsTruncated = true
+	}
+	return merged, nil
+}
+
+// AnonGetObject - see GetObject.
+func (s *xlSets) AnonGetObject(bucket, object string, startOffset, length int64, writer io.Writer) error {
+	return s.setForObject(object).AnonGetObject(bucket, object, startOffset, length, writer)
+}
+
+// AnonGetObjectInfo - see GetObjectInfo.
+func (s *xlSets) AnonGetObjectInfo(bucket, object string) (ObjectInfo, error) {
+	return s.setForObject(object).AnonGetObjectInfo(bucket, object)
+}
+
+// AnonPutObject - see PutObject.
+func (s *xlSets) AnonPutObject(bucket, object string, size int64, data io.Reader, metadata map[string]string) (ObjectInfo, error) {
+	return s.setForObject(object).AnonPutObject(bucket, object, size, data, metadata)
+}
+
+// AnonListObjects - see ListObjects.
+func (s *xlSets) AnonListObjects(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
+	var merged ListObjectsInfo
+	for _, set := range s.sets {
+		result, err := set.AnonListObjects(bucket, prefix, marker, delimiter, maxKeys)
+		if err != nil {
+			return ListObjectsInfo{}, err
+		}
+		merged.Objects = append(merged.Objects, result.Objects...)
+		if result.IsTruncated {
+			merged.IsTruncated = true
+		}
+	}
+	if len(merged.Objects) > maxKeys {
+		merged.Objects = merged.Objects[:maxKeys]
+		merged.IsTruncated = true
+	}
+	return merged, nil
+}
+
+// SetBucketPolicy - buckets are mirrored across every set, so the
+// policy is set on every set the same way MakeBucket creates the
+// bucket on every set.
+func (s *xlSets) SetBucketPolicy(bucket string, bucketPolicy policy.BucketAccessPolicy) error {
+	for _, set := range s.sets {
+		if err := set.SetBucketPolicy(bucket, bucketPolicy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetBucketPolicy - buckets are mirrored across every set, so the
+// first set's view is authoritative; see GetBucketInfo.
+func (s *xlSets) GetBucketPolicy(bucket string) (policy.BucketAccessPolicy, error) {
+	return s.sets[0].GetBucketPolicy(bucket)
+}
+
+// DeleteBucketPolicy - see SetBucketPolicy.
+func (s *xlSets) DeleteBucketPolicy(bucket string) error {
+	for _, set := range s.sets {
+		if err := set.DeleteBucketPolicy(bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}